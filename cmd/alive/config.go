@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/keypad/alive/pkg/alive"
+)
+
+var settings = map[string]string{
+	"timeout":         "timeout",
+	"connect-timeout": "connect-timeout",
+	"workers":         "workers",
+	"retries":         "retries",
+	"method":          "method",
+	"headers":         "header",
+	"expect":          "expect",
+	"insecure":        "insecure",
+	"user-agent":      "user-agent",
+	"format":          "format",
+}
+
+func runconfig(args []string) error {
+	path, rest, err := pluck(args, "config")
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		path = "alive.yaml"
+	}
+	pre, urls, err := config(path)
+	if err != nil {
+		return err
+	}
+	opt, more, err := parseflags(append(pre, rest...))
+	if err != nil {
+		return err
+	}
+	urls = append(urls, more...)
+	if len(urls) == 0 {
+		return errors.New("no targets in config")
+	}
+	return poll(urls, opt, alive.HTTP)
+}
+
+func pluck(args []string, name string) (string, []string, error) {
+	var rest []string
+	value := ""
+	for i := 0; i < len(args); i++ {
+		item := strings.TrimLeft(args[i], "-")
+		switch {
+		case args[i] == "--":
+			return value, append(rest, args[i:]...), nil
+		case item == name && args[i] != item:
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("flag needs an argument: -%s", name)
+			}
+			value = args[i+1]
+			i++
+		case strings.HasPrefix(item, name+"=") && args[i] != item:
+			value = strings.TrimPrefix(item, name+"=")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return value, rest, nil
+}
+
+func config(path string) ([]string, []string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	var keys, urls []string
+	values := map[string][]string{}
+	key := ""
+	scan := bufio.NewScanner(file)
+	for num := 1; scan.Scan(); num++ {
+		raw := scan.Text()
+		line := strings.TrimSpace(uncomment(raw))
+		if line == "" {
+			continue
+		}
+		var list []string
+		if raw[0] != ' ' && raw[0] != '\t' {
+			name, text, ok := strings.Cut(line, ":")
+			if !ok {
+				return nil, nil, fmt.Errorf("%s:%d: expected key: value", path, num)
+			}
+			key = strings.TrimSpace(name)
+			if key != "targets" && settings[key] == "" {
+				return nil, nil, fmt.Errorf("%s:%d: unknown key %q", path, num, key)
+			}
+			if _, ok := values[key]; !ok && key != "targets" {
+				keys = append(keys, key)
+				values[key] = nil
+			}
+			list = inline(strings.TrimSpace(text))
+		} else {
+			item, ok := strings.CutPrefix(line, "- ")
+			if !ok && key == "headers" && strings.Contains(line, ":") {
+				item, ok = line, true
+			}
+			if key == "" || !ok {
+				return nil, nil, fmt.Errorf("%s:%d: expected a list item under a key", path, num)
+			}
+			list = []string{unquote(strings.TrimSpace(item))}
+		}
+		if key == "targets" {
+			urls = append(urls, list...)
+			continue
+		}
+		values[key] = append(values[key], list...)
+	}
+	if err := scan.Err(); err != nil {
+		return nil, nil, err
+	}
+	var args []string
+	for _, key := range keys {
+		list := values[key]
+		if key != "headers" {
+			list = []string{strings.Join(list, ",")}
+		}
+		for _, item := range list {
+			arg := "--" + settings[key] + "=" + item
+			if _, _, err := parseflags([]string{arg}); err != nil {
+				return nil, nil, fmt.Errorf("%s: %s: %w", path, key, reason(err))
+			}
+			args = append(args, arg)
+		}
+	}
+	return args, urls, nil
+}
+
+func reason(err error) error {
+	if _, text, ok := strings.Cut(err.Error(), ": "); ok && strings.HasPrefix(err.Error(), "invalid value") {
+		return errors.New(text)
+	}
+	return err
+}
+
+func inline(text string) []string {
+	if text == "" {
+		return nil
+	}
+	if !strings.HasPrefix(text, "[") || !strings.HasSuffix(text, "]") {
+		return []string{unquote(text)}
+	}
+	var list []string
+	for _, part := range strings.Split(text[1:len(text)-1], ",") {
+		if part = unquote(strings.TrimSpace(part)); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+func unquote(text string) string {
+	if len(text) >= 2 && (text[0] == '"' || text[0] == '\'') && text[len(text)-1] == text[0] {
+		return text[1 : len(text)-1]
+	}
+	return text
+}
+
+func uncomment(line string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return ""
+	}
+	if at := strings.Index(line, " #"); at >= 0 {
+		return line[:at]
+	}
+	return line
+}
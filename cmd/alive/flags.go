@@ -0,0 +1,582 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/keypad/alive/pkg/alive"
+)
+
+type options struct {
+	alive.Options
+	detail   bool
+	format   string
+	pretty   bool
+	json     bool
+	digest   bool
+	failon   map[string]bool
+	summary  bool
+	brief    bool
+	only     map[string]bool
+	color    bool
+	every    time.Duration
+	count    int
+	targets  string
+	order    string
+	desc     bool
+	group    bool
+	out      string
+	silent   bool
+	history  string
+	changes  bool
+	webhook  *notifier
+	statsd   *statsd
+	proto    bool
+	hops     bool
+	spread   bool
+	origins  []string
+	token    string
+	hosts    []string
+	cidrs    []*net.IPNet
+	private  bool
+	certinfo bool
+	progress bool
+	stream   bool
+	feed     chan alive.Result
+	ctx      context.Context
+	quiet    bool
+	zero     bool
+}
+
+type headers http.Header
+
+func (h headers) String() string {
+	return ""
+}
+
+func (h headers) Set(raw string) error {
+	key, text, ok := strings.Cut(raw, ":")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("header %q must look like \"Key: Value\"", raw)
+	}
+	http.Header(h).Set(key, strings.TrimSpace(text))
+	return nil
+}
+
+type resolves map[string]string
+
+func (r resolves) String() string {
+	return ""
+}
+
+func (r resolves) Set(raw string) error {
+	host, rest, ok := strings.Cut(raw, ":")
+	port, addr, ok2 := strings.Cut(rest, ":")
+	ip := net.ParseIP(strings.Trim(addr, "[]"))
+	if !ok || !ok2 || host == "" || port == "" || ip == nil {
+		return fmt.Errorf("resolve %q must look like host:port:ip", raw)
+	}
+	r[strings.ToLower(net.JoinHostPort(host, port))] = ip.String()
+	return nil
+}
+
+func parseflags(args []string) (options, []string, error) {
+	opt := options{Options: alive.Defaults()}
+	opt.Headers = http.Header{}
+	opt.Resolve = map[string]string{}
+	set := flag.NewFlagSet("alive", flag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	set.BoolVar(&opt.Normalize, "normalize", false, "")
+	set.BoolVar(&opt.AutoScheme, "auto-scheme", false, "")
+	set.BoolVar(&opt.HTTPFallback, "http-fallback", false, "")
+	set.BoolVar(&opt.StrictURL, "strict-url", false, "")
+	set.BoolVar(&opt.Shuffle, "shuffle", false, "")
+	set.Uint64Var(&opt.Seed, "seed", 0, "")
+	set.BoolVar(&opt.KeepOrder, "preserve-order", false, "")
+	set.DurationVar(&opt.every, "interval", 0, "")
+	set.StringVar(&opt.targets, "targets", "", "")
+	set.StringVar(&opt.token, "auth-token", "", "")
+	set.BoolVar(&opt.private, "allow-private", false, "")
+	set.Func("allow-host", "", func(raw string) error {
+		opt.hosts = append(opt.hosts, strings.ToLower(strings.TrimSpace(raw)))
+		return nil
+	})
+	set.Func("allow-cidr", "", func(raw string) error {
+		_, block, err := net.ParseCIDR(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		opt.cidrs = append(opt.cidrs, block)
+		return nil
+	})
+	set.Func("cors-origin", "", func(raw string) error {
+		for _, part := range strings.Split(raw, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				opt.origins = append(opt.origins, part)
+			}
+		}
+		return nil
+	})
+	set.IntVar(&opt.count, "count", 0, "")
+	set.Func("timeout", "", func(raw string) error {
+		span, err := alive.ParseTimeout(raw)
+		opt.Timeout = span
+		return err
+	})
+	set.Func("connect-timeout", "", func(raw string) error {
+		span, err := alive.ParseTimeout(raw)
+		opt.ConnectTimeout = span
+		return err
+	})
+	set.IntVar(&opt.Workers, "workers", 8, "")
+	set.Float64Var(&opt.Rate, "rate", 0, "")
+	set.IntVar(&opt.PerHost, "per-host", 0, "")
+	set.BoolVar(&opt.NoKeepAlive, "no-keepalive", false, "")
+	set.DurationVar(&opt.Jitter, "jitter", 0, "")
+	polite := set.Bool("polite", false, "")
+	gap := set.Duration("polite-delay", time.Second, "")
+	set.IntVar(&opt.BatchSize, "batch-size", 0, "")
+	set.DurationVar(&opt.BatchPause, "batch-pause", 0, "")
+	set.StringVar(&opt.ALPN, "alpn", "", "")
+	set.BoolVar(&opt.detail, "detail", false, "")
+	set.BoolVar(&opt.certinfo, "cert-info", false, "")
+	set.BoolVar(&opt.Trace, "trace", false, "")
+	set.BoolVar(&opt.proto, "show-proto", false, "")
+	set.BoolVar(&opt.hops, "show-redirects", false, "")
+	one := set.Bool("http1", false, "")
+	two := set.Bool("http2", false, "")
+	set.IntVar(&opt.Retries, "retries", 0, "")
+	set.BoolVar(&opt.NoRedirect, "no-redirect", false, "")
+	set.IntVar(&opt.MaxRedirects, "max-redirects", 10, "")
+	set.StringVar(&opt.Method, "method", http.MethodGet, "")
+	set.Func("body", "", func(raw string) error {
+		opt.Body = []byte(raw)
+		return nil
+	})
+	set.Func("body-file", "", func(raw string) error {
+		data, err := os.ReadFile(raw)
+		opt.Body = data
+		return err
+	})
+	set.Var(headers(opt.Headers), "header", "")
+	set.StringVar(&opt.UserAgent, "user-agent", "alive/1", "")
+	set.Func("cookie", "", func(raw string) error {
+		list, err := http.ParseCookie(raw)
+		if err != nil {
+			return fmt.Errorf("cookie %q must look like name=value", raw)
+		}
+		opt.Cookies = append(opt.Cookies, list...)
+		return nil
+	})
+	set.StringVar(&opt.BasicAuth, "basic-auth", "", "")
+	set.BoolVar(&opt.Insecure, "insecure", false, "")
+	certfile := set.String("client-cert", "", "")
+	keyfile := set.String("client-key", "", "")
+	set.Var(resolves(opt.Resolve), "resolve", "")
+	set.Func("proxy", "", func(raw string) error {
+		part, err := url.Parse(raw)
+		if err != nil || part.Host == "" {
+			return errors.New("proxy must be a url like http://proxy:3128")
+		}
+		switch part.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return errors.New("proxy scheme must be http, https or socks5")
+		}
+		opt.Proxy = part
+		return nil
+	})
+	set.Func("statsd", "", func(raw string) error {
+		if _, _, err := net.SplitHostPort(raw); err != nil {
+			return errors.New("statsd must be host:port")
+		}
+		opt.statsd = &statsd{addr: raw}
+		return nil
+	})
+	set.Func("webhook", "", func(raw string) error {
+		part, err := url.Parse(raw)
+		if err != nil || part.Host == "" || part.Scheme != "http" && part.Scheme != "https" {
+			return errors.New("webhook must be an http or https url")
+		}
+		opt.webhook = &notifier{url: raw}
+		return nil
+	})
+	four := set.Bool("ipv4", false, "")
+	six := set.Bool("ipv6", false, "")
+	set.IntVar(&opt.CertWarnDays, "cert-warn-days", 0, "")
+	set.Func("pin-sha256", "", func(raw string) error {
+		raw = strings.TrimPrefix(strings.TrimSpace(raw), "sha256/")
+		sum, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil || len(sum) != 32 {
+			return errors.New("pin must be a base64 sha256 digest")
+		}
+		if opt.Pins == nil {
+			opt.Pins = map[string]bool{}
+		}
+		opt.Pins[raw] = true
+		return nil
+	})
+	set.BoolVar(&opt.AllIPs, "all-ips", false, "")
+	set.IntVar(&opt.WarnAt, "warn-at", 400, "")
+	set.Func("slow-at", "", func(raw string) error {
+		span, err := alive.ParseTimeout(raw)
+		opt.SlowAt = span
+		return err
+	})
+	set.Func("retry-on", "", func(raw string) error {
+		set, err := triggers(raw)
+		opt.RetryOn = set
+		return err
+	})
+	set.Func("warn-on", "", func(raw string) error {
+		set, err := kinds(raw)
+		opt.WarnOn = set
+		return err
+	})
+	set.Func("expect", "", func(raw string) error {
+		set, err := alive.ParseCodes(raw)
+		opt.Expect = set
+		return err
+	})
+	set.StringVar(&opt.ExpectBody, "expect-body", "", "")
+	set.Func("expect-regex", "", func(raw string) error {
+		re, err := regexp.Compile(raw)
+		opt.ExpectRegex = re
+		return err
+	})
+	set.Int64Var(&opt.MaxBody, "max-body", 1<<20, "")
+	set.BoolVar(&opt.MeasureBody, "measure-body", false, "")
+	set.Int64Var(&opt.MaxTotalBytes, "max-total-bytes", 0, "")
+	check := set.String("assert", "", "")
+	fail := set.String("fail-on", "warn,down,invalid", "")
+	set.BoolVar(&opt.zero, "exit-always-zero", false, "")
+	only := set.String("only", "", "")
+	tagged := set.String("filter-tag", "", "")
+	plain := set.Bool("no-color", false, "")
+	still := set.Bool("no-progress", false, "")
+	set.StringVar(&opt.format, "format", "table", "")
+	set.BoolVar(&opt.json, "json", false, "")
+	set.BoolVar(&opt.pretty, "json-pretty", false, "")
+	set.BoolVar(&opt.digest, "digest", false, "")
+	order := set.String("sort", "", "")
+	set.StringVar(&opt.out, "out", "", "")
+	set.BoolVar(&opt.silent, "out-only", false, "")
+	set.BoolVar(&opt.stream, "stream", false, "")
+	set.StringVar(&opt.history, "history", "", "")
+	prev := set.String("baseline", "", "")
+	set.BoolVar(&opt.changes, "only-changes", false, "")
+	set.BoolVar(&opt.spread, "percentiles", false, "")
+	set.BoolVar(&opt.summary, "summary", false, "")
+	set.BoolVar(&opt.quiet, "quiet", false, "")
+	set.BoolVar(&opt.group, "group-by-host", false, "")
+	set.BoolVar(&opt.brief, "summary-only", false, "")
+	var failed error
+	given := named(args)
+	set.VisitAll(func(f *flag.Flag) {
+		name := "ALIVE_" + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		raw, ok := os.LookupEnv(name)
+		if !ok || failed != nil || given[f.Name] {
+			return
+		}
+		if err := set.Set(f.Name, raw); err != nil {
+			failed = fmt.Errorf("%s: invalid value %q: %w", name, raw, err)
+		}
+	})
+	if failed != nil {
+		return options{}, nil, failed
+	}
+	var tail []string
+	for i, item := range args {
+		if item == "--" {
+			tail = args[i+1:]
+			args = args[:i]
+			break
+		}
+	}
+	var rest []string
+	for {
+		if err := set.Parse(args); err != nil {
+			return options{}, nil, err
+		}
+		args = set.Args()
+		if len(args) == 0 {
+			break
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+	rest = append(rest, tail...)
+	var err error
+	if opt.failon, err = states(*fail); err != nil {
+		return options{}, nil, err
+	}
+	for _, tag := range strings.Split(*tagged, ",") {
+		if tag = strings.TrimPrefix(strings.TrimSpace(tag), "@"); tag != "" {
+			if opt.Tags == nil {
+				opt.Tags = map[string]bool{}
+			}
+			opt.Tags[tag] = true
+		}
+	}
+	if *only == "" && opt.quiet {
+		*only = "warn,down,invalid"
+	}
+	if opt.only, err = states(*only); err != nil {
+		return options{}, nil, err
+	}
+	if *check != "" {
+		fn, err := alive.Assert(*check)
+		if err != nil {
+			return options{}, nil, err
+		}
+		opt.Assert = fn
+	}
+	switch {
+	case *one && *two:
+		return options{}, nil, errors.New("http1 and http2 cannot be combined")
+	case *one:
+		opt.HTTPVersion = "1"
+	case *two:
+		opt.HTTPVersion = "2"
+	}
+	switch {
+	case *four && *six:
+		return options{}, nil, errors.New("ipv4 and ipv6 cannot be combined")
+	case *four:
+		opt.Family = "tcp4"
+	case *six:
+		opt.Family = "tcp6"
+	}
+	opt.color = !*plain && os.Getenv("NO_COLOR") == "" && terminal(os.Stdout)
+	if opt.json || opt.pretty {
+		opt.format = "json"
+	}
+	if opt.digest {
+		opt.format = "digest"
+	}
+	switch opt.format {
+	case "table", "json", "csv", "digest":
+	default:
+		return options{}, nil, errors.New("format must be table, json, csv or digest")
+	}
+	opt.progress = !*still && opt.format != "json" && terminal(os.Stdout)
+	if opt.silent && opt.out == "" {
+		return options{}, nil, errors.New("out-only needs --out")
+	}
+	if opt.out != "" {
+		probe, err := os.CreateTemp(filepath.Dir(opt.out), ".alive-*")
+		if err != nil {
+			return options{}, nil, fmt.Errorf("cannot write %s: %w", opt.out, err)
+		}
+		probe.Close()
+		os.Remove(probe.Name())
+	}
+	if *prev != "" {
+		if opt.Baseline, err = baseline(*prev); err != nil {
+			return options{}, nil, err
+		}
+	}
+	if opt.changes && opt.Baseline == nil {
+		return options{}, nil, errors.New("only-changes needs --baseline")
+	}
+	if opt.history != "" {
+		file, err := os.OpenFile(opt.history, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return options{}, nil, err
+		}
+		file.Close()
+	}
+	key, dir, _ := strings.Cut(*order, ":")
+	switch key {
+	case "", "latency", "status", "target", "state":
+	default:
+		return options{}, nil, errors.New("sort must be latency, status, target or state")
+	}
+	switch dir {
+	case "", "asc", "desc":
+	default:
+		return options{}, nil, errors.New("sort direction must be asc or desc")
+	}
+	opt.order, opt.desc = key, dir == "desc"
+	if opt.stream && (opt.format != "table" || opt.order != "" || opt.brief) {
+		return options{}, nil, errors.New("stream prints the plain table and cannot be combined with --sort, --summary-only or another format")
+	}
+	if opt.stream {
+		opt.progress = false
+	}
+	opt.Method = strings.ToUpper(opt.Method)
+	switch opt.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		if opt.Body != nil {
+			return options{}, nil, fmt.Errorf("%s requests cannot carry a body", opt.Method)
+		}
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return options{}, nil, errors.New("method must be GET, HEAD, OPTIONS, POST, PUT, PATCH or DELETE")
+	}
+	if opt.MaxBody < 0 {
+		return options{}, nil, errors.New("max body must not be negative")
+	}
+	if opt.MaxBody == 0 {
+		opt.MaxBody = -1
+	}
+	if opt.UserAgent == "" {
+		opt.UserAgent = "-"
+	}
+	if (opt.ExpectBody != "" || opt.ExpectRegex != nil || opt.MeasureBody) && opt.Method == http.MethodHead {
+		return options{}, nil, errors.New("body assertions need a method with a body, not HEAD")
+	}
+	if opt.MaxTotalBytes < 0 {
+		return options{}, nil, errors.New("max total bytes must not be negative")
+	}
+	if (*certfile == "") != (*keyfile == "") {
+		return options{}, nil, errors.New("client-cert and client-key must be used together")
+	}
+	if *certfile != "" {
+		pair, err := tls.LoadX509KeyPair(*certfile, *keyfile)
+		if err != nil {
+			return options{}, nil, fmt.Errorf("client certificate: %w", err)
+		}
+		opt.ClientCert = &pair
+	}
+	if opt.BasicAuth != "" && !strings.Contains(opt.BasicAuth, ":") {
+		return options{}, nil, errors.New("basic auth must look like user:pass")
+	}
+	if opt.CertWarnDays < 0 {
+		return options{}, nil, errors.New("cert warn days must not be negative")
+	}
+	if *polite && *gap <= 0 {
+		return options{}, nil, errors.New("polite delay must be positive")
+	}
+	if *polite {
+		opt.Polite = *gap
+	}
+	if opt.Seed != 0 && !opt.Shuffle {
+		return options{}, nil, errors.New("seed needs --shuffle")
+	}
+	if opt.HTTPFallback && !opt.AutoScheme {
+		return options{}, nil, errors.New("http fallback needs --auto-scheme")
+	}
+	if opt.Retries < 0 {
+		return options{}, nil, errors.New("retries must not be negative")
+	}
+	if opt.MaxRedirects < 0 {
+		return options{}, nil, errors.New("max redirects must not be negative")
+	}
+	if opt.MaxRedirects == 0 {
+		opt.MaxRedirects = -1
+	}
+	if opt.every < 0 || opt.count < 0 {
+		return options{}, nil, errors.New("interval and count must not be negative")
+	}
+	if opt.count > 0 && opt.every == 0 {
+		return options{}, nil, errors.New("count needs --interval")
+	}
+	if opt.Workers < 1 {
+		return options{}, nil, errors.New("workers must be at least 1")
+	}
+	if opt.WarnAt < 100 || opt.WarnAt > 600 {
+		return options{}, nil, errors.New("warn-at must be a status code between 100 and 600")
+	}
+	if math.IsNaN(opt.Rate) || opt.Rate < 0 || opt.Rate > 1e6 || (opt.Rate > 0 && opt.Rate < 1e-3) {
+		return options{}, nil, errors.New("rate must be 0 or between 0.001 and 1000000 per second")
+	}
+	if opt.Jitter < 0 {
+		return options{}, nil, errors.New("jitter must not be negative")
+	}
+	if opt.PerHost < 0 {
+		return options{}, nil, errors.New("per host must not be negative")
+	}
+	if opt.BatchSize < 0 {
+		return options{}, nil, errors.New("batch size must not be negative")
+	}
+	if opt.BatchPause < 0 {
+		return options{}, nil, errors.New("batch pause must not be negative")
+	}
+	return opt, rest, nil
+}
+
+// named returns the flags set in args, so ALIVE_* values for them are skipped
+// rather than added to repeatable flags like --header.
+func named(args []string) map[string]bool {
+	seen := map[string]bool{}
+	for _, item := range args {
+		if item == "--" {
+			break
+		}
+		if !strings.HasPrefix(item, "-") || item == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(item, "-"), "=")
+		seen[name] = true
+	}
+	return seen
+}
+
+func states(raw string) (map[string]bool, error) {
+	set := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "", "none":
+		case "up", "warn", "down", "invalid":
+			set[part] = true
+		default:
+			return nil, fmt.Errorf("unknown state: %s", part)
+		}
+	}
+	return set, nil
+}
+
+func kinds(raw string) (map[string]bool, error) {
+	set := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "":
+		case "timeout", "connect-timeout", "body-timeout", "dns", "refused", "reset", "eof", "unreachable", "tls", "proxy", "no-address", "error":
+			set[part] = true
+		default:
+			return nil, fmt.Errorf("unknown error kind: %s", part)
+		}
+	}
+	return set, nil
+}
+
+func triggers(raw string) (map[string]bool, error) {
+	set := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "":
+		case "timeout", "5xx", "429", "dns", "refused", "reset", "eof", "unreachable", "tls", "error":
+			set[part] = true
+		default:
+			return nil, fmt.Errorf("unknown retry condition: %s", part)
+		}
+	}
+	return set, nil
+}
+
+func terminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
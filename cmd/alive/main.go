@@ -2,32 +2,34 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
-	"net/http"
-	"net/url"
+	"io"
 	"os"
-	"sort"
-	"strconv"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"strings"
-	"sync"
 	"time"
+
+	"github.com/keypad/alive/pkg/alive"
 )
 
-type row struct {
-	target string
-	state  string
-	code   int
-	span   time.Duration
-	size   int64
-	issue  string
-}
+var (
+	version = "dev"
+	commit  = ""
+)
 
 func main() {
 	if err := run(os.Args[1:]); err != nil {
 		fmt.Fprintln(os.Stderr, "error:", err)
+		var fail *failure
+		if errors.As(err, &fail) {
+			os.Exit(fail.code)
+		}
 		os.Exit(1)
 	}
 }
@@ -38,114 +40,259 @@ func run(args []string) error {
 		return nil
 	}
 	mode := args[0]
+	if mode == "help" {
+		printhelp()
+		return nil
+	}
+	if mode == "run" {
+		return runconfig(args[1:])
+	}
+	opt, rest, err := parseflags(args[1:])
+	if err != nil {
+		return err
+	}
 	switch mode {
 	case "check":
-		return runcheck(args[1:])
+		return runcheck(rest, opt, alive.HTTP)
 	case "file":
-		return runfile(args[1:])
+		return runfile(rest, opt)
+	case "tcp":
+		opt.AutoScheme, opt.HTTPFallback = false, false
+		return runcheck(rest, opt, alive.TCP)
+	case "dns":
+		opt.AutoScheme, opt.HTTPFallback = false, false
+		return runcheck(rest, opt, alive.DNS)
 	case "serve":
-		return runserve(args[1:])
-	case "help":
-		printhelp()
-		return nil
+		return runserve(rest, opt)
+	case "tui":
+		return runtui(rest, opt)
 	default:
 		return fmt.Errorf("unknown mode: %s", mode)
 	}
 }
 
-func runcheck(args []string) error {
+func runcheck(args []string, opt options, probe alive.Probe) error {
 	if len(args) == 0 {
 		return errors.New("missing urls")
 	}
-	urls, span, err := spliturls(args, 3500*time.Millisecond)
+	urls, span, err := spliturls(args, opt.Timeout)
 	if err != nil {
 		return err
 	}
-	rows := checkmany(urls, span)
-	fmt.Print(render(rows))
-	return nil
+	opt.Timeout = span
+	if slices.Contains(urls, "-") {
+		piped, err := read(os.Stdin)
+		if err != nil {
+			return err
+		}
+		urls = slices.DeleteFunc(slices.Clone(urls), func(item string) bool { return item == "-" })
+		urls = append(urls, piped...)
+	}
+	return poll(urls, opt, probe)
 }
 
-func runfile(args []string) error {
+func runfile(args []string, opt options) error {
 	if len(args) == 0 {
 		return errors.New("missing file path")
 	}
-	path := args[0]
-	span := 3500 * time.Millisecond
-	if len(args) > 1 {
-		part, err := parsems(args[1])
+	paths := args
+	last := strings.TrimSpace(args[len(args)-1])
+	if _, err := os.Stat(last); err != nil && maybe(last) {
+		part, err := alive.ParseTimeout(last)
 		if err != nil {
 			return err
 		}
-		span = part
+		opt.Timeout = part
+		paths = args[:len(args)-1]
 	}
-	urls, err := load(path)
-	if err != nil {
-		return err
+	if len(paths) == 0 {
+		return errors.New("missing file path")
+	}
+	var urls []string
+	for _, pattern := range paths {
+		names := []string{pattern}
+		if pattern != "-" && strings.ContainsAny(pattern, "*?[") {
+			found, err := filepath.Glob(pattern)
+			if err != nil {
+				return fmt.Errorf("bad pattern %s: %w", pattern, err)
+			}
+			if len(found) == 0 {
+				return fmt.Errorf("no files match %s", pattern)
+			}
+			names = found
+		}
+		for _, name := range names {
+			list, err := load(name)
+			if err != nil {
+				return err
+			}
+			urls = append(urls, list...)
+		}
 	}
+	urls = alive.Clean(urls, true)
 	if len(urls) == 0 {
 		return errors.New("no urls in file")
 	}
-	rows := checkmany(urls, span)
-	fmt.Print(render(rows))
-	return nil
+	return poll(urls, opt, alive.HTTP)
 }
 
-func runserve(args []string) error {
-	port := "4177"
-	span := 3500 * time.Millisecond
-	if len(args) > 0 {
-		port = args[0]
-	}
-	if len(args) > 1 {
-		part, err := parsems(args[1])
-		if err != nil {
+func poll(urls []string, opt options, probe alive.Probe) error {
+	if opt.every == 0 {
+		rows := gather(urls, opt, probe, "")
+		if !opt.silent && !opt.stream {
+			fmt.Print(output(rows, opt))
+		}
+		if err := save(rows, opt); err != nil {
 			return err
 		}
-		span = part
-	}
-	addr := ":" + port
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		fmt.Fprintln(w, "alive")
-		fmt.Fprintln(w, "")
-		fmt.Fprintln(w, "try:")
-		fmt.Fprintln(w, "  /check?url=https://example.com")
-		fmt.Fprintln(w, "  /check?url=https://example.com&url=https://go.dev")
-		fmt.Fprintln(w, "  /check?url=https://example.com&timeout=1200")
-	})
-	mux.HandleFunc("/check", func(w http.ResponseWriter, r *http.Request) {
-		query := r.URL.Query()["url"]
-		if len(query) == 0 {
-			if one := strings.TrimSpace(r.URL.Query().Get("target")); one != "" {
-				query = []string{one}
-			}
+		if err := remember(rows, opt); err != nil {
+			return err
 		}
-		if len(query) == 0 {
-			http.Error(w, "missing url query", http.StatusBadRequest)
-			return
+		opt.statsd.push(rows)
+		return verdict(rows, opt)
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	defer signal.Stop(stop)
+	plain := opt.format == "table" || opt.format == "digest"
+	var rows []alive.Result
+	seen := samples{}
+	cycle := 0
+	for {
+		cycle++
+		lead := ""
+		if plain && !opt.silent && opt.stream {
+			lead = fmt.Sprintf("# %s cycle %d\n", time.Now().Format(time.RFC3339), cycle)
 		}
-		used := span
-		if raw := strings.TrimSpace(r.URL.Query().Get("timeout")); raw != "" {
-			part, err := parsems(raw)
-			if err != nil {
-				http.Error(w, "invalid timeout", http.StatusBadRequest)
-				return
-			}
-			used = part
+		rows = gather(urls, opt, probe, lead)
+		seen.add(rows)
+		opt.webhook.observe(rows)
+		hush := opt.quiet && allup(rows)
+		if plain && !opt.silent && !opt.stream && !hush {
+			fmt.Printf("# %s cycle %d\n", time.Now().Format(time.RFC3339), cycle)
+		}
+		if !opt.silent && !opt.stream {
+			fmt.Print(output(rows, opt))
+		}
+		if plain && !opt.silent && opt.spread && !hush {
+			fmt.Print(seen.table())
+		}
+		if err := save(rows, opt); err != nil {
+			return err
+		}
+		if err := remember(rows, opt); err != nil {
+			return err
+		}
+		opt.statsd.push(rows)
+		if opt.count > 0 && cycle >= opt.count {
+			break
+		}
+		wait := time.NewTimer(opt.every)
+		select {
+		case <-wait.C:
+			continue
+		case <-stop:
+			wait.Stop()
+		}
+		break
+	}
+	opt.webhook.flush()
+	if plain && !opt.silent && !(opt.quiet && allup(rows)) {
+		fmt.Printf("# %d cycles, last: %s", cycle, summary(rows))
+		fmt.Print(seen.table())
+	}
+	return verdict(rows, opt)
+}
+
+func save(rows []alive.Result, opt options) error {
+	if opt.out == "" {
+		return nil
+	}
+	used := opt
+	used.color = false
+	switch strings.ToLower(filepath.Ext(opt.out)) {
+	case ".json":
+		used.format = "json"
+	case ".csv":
+		used.format = "csv"
+	default:
+		used.format = "table"
+	}
+	temp, err := os.CreateTemp(filepath.Dir(opt.out), ".alive-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.WriteString(output(rows, used)); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Chmod(0o644); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), opt.out)
+}
+
+func remember(rows []alive.Result, opt options) error {
+	if opt.history == "" {
+		return nil
+	}
+	file, err := os.OpenFile(opt.history, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	at := time.Now().UTC()
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, rec := range records(rows, opt) {
+		rec.Run = &at
+		enc.Encode(rec)
+	}
+	if _, err := file.Write(b.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+var errfail = errors.New("checks failed")
+
+type failure struct {
+	code  int
+	count int
+	total int
+}
+
+func (f *failure) Error() string {
+	return fmt.Sprintf("%v: %d of %d targets", errfail, f.count, f.total)
+}
+
+func (f *failure) Unwrap() error {
+	return errfail
+}
+
+func verdict(rows []alive.Result, opt options) error {
+	if opt.zero {
+		return nil
+	}
+	fail := &failure{code: 1, total: len(rows)}
+	for _, item := range rows {
+		if !opt.failon[item.State] {
+			continue
 		}
-		rows := checkmany(query, used)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		fmt.Fprint(w, render(rows))
-	})
-	srv := &http.Server{
-		Addr:              addr,
-		Handler:           mux,
-		ReadHeaderTimeout: 2 * time.Second,
-	}
-	fmt.Printf("alive serving on %s\n", addr)
-	return srv.ListenAndServe()
+		fail.count++
+		if item.State != "warn" {
+			fail.code = 2
+		}
+	}
+	if fail.count == 0 {
+		return nil
+	}
+	return fail
 }
 
 func spliturls(args []string, base time.Duration) ([]string, time.Duration, error) {
@@ -156,7 +303,7 @@ func spliturls(args []string, base time.Duration) ([]string, time.Duration, erro
 	urls := args
 	last := strings.TrimSpace(args[len(args)-1])
 	if maybe(last) {
-		part, err := parsems(last)
+		part, err := alive.ParseTimeout(last)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -173,6 +320,9 @@ func maybe(raw string) bool {
 	if raw == "" {
 		return false
 	}
+	if _, err := time.ParseDuration(raw); err == nil {
+		return true
+	}
 	for _, ch := range raw {
 		if ch < '0' || ch > '9' {
 			return false
@@ -181,200 +331,234 @@ func maybe(raw string) bool {
 	return true
 }
 
-func parsems(raw string) (time.Duration, error) {
-	count, err := strconv.Atoi(strings.TrimSpace(raw))
-	if err != nil || count <= 0 {
-		return 0, errors.New("timeout must be positive milliseconds")
-	}
-	if count > 120000 {
-		return 0, errors.New("timeout too large")
-	}
-	return time.Duration(count) * time.Millisecond, nil
-}
-
 func load(path string) ([]string, error) {
+	if path == "-" {
+		return read(os.Stdin)
+	}
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
-	set := map[string]struct{}{}
-	scan := bufio.NewScanner(file)
-	for scan.Scan() {
-		line := strings.TrimSpace(scan.Text())
+	list, err := read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return list, nil
+}
+
+func read(src io.Reader) ([]string, error) {
+	var list []string
+	scan := bufio.NewScanner(src)
+	for num := 1; scan.Scan(); num++ {
+		line := strings.Join(strings.Fields(scan.Text()), " ")
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
-		set[line] = struct{}{}
+		if _, _, err := alive.Tune(line, alive.Options{}); err != nil {
+			return nil, fmt.Errorf("line %d: %w", num, err)
+		}
+		list = append(list, line)
 	}
 	if err := scan.Err(); err != nil {
 		return nil, err
 	}
-	list := make([]string, 0, len(set))
-	for item := range set {
-		list = append(list, item)
-	}
-	sort.Strings(list)
-	return list, nil
+	return alive.Clean(list, true), nil
 }
 
-func checkmany(input []string, span time.Duration) []row {
-	urls := clean(input)
-	rows := make([]row, len(urls))
-	if len(urls) == 0 {
-		return rows
-	}
-	count := len(urls)
-	workers := 8
-	if count < workers {
-		workers = count
-	}
-	type job struct {
-		index int
-		item  string
-	}
-	queue := make(chan job)
-	var wait sync.WaitGroup
-	for i := 0; i < workers; i++ {
-		wait.Add(1)
-		go func() {
-			defer wait.Done()
-			for task := range queue {
-				rows[task.index] = check(task.item, span)
+// gather checks urls, printing rows as they finish in --stream mode. lead
+// and the column header go out before the first row; with --quiet they wait
+// until a row is shown at all.
+func gather(urls []string, opt options, probe alive.Probe, lead string) []alive.Result {
+	if !opt.stream || opt.silent {
+		return checkmany(urls, opt, probe)
+	}
+	feed := make(chan alive.Result)
+	done := make(chan struct{})
+	opt.feed = feed
+	go func() {
+		defer close(done)
+		head := columns(nil, opt)
+		headed := !opt.quiet
+		if headed {
+			fmt.Print(lead)
+			fmt.Println(strings.Join(head, "\t"))
+		}
+		for item := range feed {
+			if !visible(item, opt) {
+				continue
 			}
-		}()
-	}
-	for i, item := range urls {
-		queue <- job{index: i, item: item}
-	}
-	close(queue)
-	wait.Wait()
-	return rows
-}
-
-func clean(input []string) []string {
-	set := map[string]struct{}{}
-	for _, raw := range input {
-		item := strings.TrimSpace(raw)
-		if item == "" {
-			continue
+			if !headed {
+				fmt.Print(lead)
+				fmt.Println(strings.Join(head, "\t"))
+				headed = true
+			}
+			fmt.Println(line(item, head, opt))
 		}
-		set[item] = struct{}{}
-	}
-	list := make([]string, 0, len(set))
-	for item := range set {
-		list = append(list, item)
-	}
-	sort.Strings(list)
-	return list
-}
-
-func check(item string, span time.Duration) row {
-	used := strings.TrimSpace(item)
-	if err := okurl(used); err != nil {
-		return row{target: used, state: "invalid", issue: err.Error()}
-	}
-	ctx, stop := context.WithTimeout(context.Background(), span)
-	defer stop()
-	start := time.Now()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, used, nil)
-	if err != nil {
-		return row{target: used, state: "invalid", issue: err.Error()}
-	}
-	req.Header.Set("User-Agent", "alive/1")
-	cli := &http.Client{Timeout: span}
-	res, err := cli.Do(req)
-	if err != nil {
-		return row{target: used, state: "down", span: time.Since(start), issue: maperr(err)}
-	}
-	defer res.Body.Close()
-	state := "up"
-	if res.StatusCode >= 400 {
-		state = "warn"
-	}
-	size := res.ContentLength
-	if size < 0 {
-		size = 0
+	}()
+	rows := checkmany(urls, opt, probe)
+	close(feed)
+	<-done
+	if opt.quiet {
+		if allup(rows) {
+			return rows
+		}
+		opt.summary = opt.format == "table"
 	}
-	return row{target: used, state: state, code: res.StatusCode, span: time.Since(start), size: size}
+	shown := slices.DeleteFunc(slices.Clone(rows), func(item alive.Result) bool { return !visible(item, opt) })
+	fmt.Print(trailer(rows, shown, opt))
+	return rows
 }
 
-func okurl(raw string) error {
-	part, err := url.ParseRequestURI(raw)
+func baseline(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return errors.New("bad url")
-	}
-	if part.Scheme != "http" && part.Scheme != "https" {
-		return errors.New("scheme must be http or https")
-	}
-	if part.Host == "" {
-		return errors.New("missing host")
-	}
-	if strings.Contains(part.Host, " ") {
-		return errors.New("bad host")
+		return nil, err
 	}
-	if _, _, err := net.SplitHostPort(part.Host); err == nil {
-		return nil
+	var list []record
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("baseline %s: %w", path, err)
 	}
-	if strings.Count(part.Host, ":") > 1 && !strings.HasPrefix(part.Host, "[") {
-		return errors.New("bad host")
+	base := map[string]string{}
+	for _, rec := range list {
+		base[rec.Target] = rec.State
 	}
-	return nil
+	return base, nil
 }
 
-func maperr(err error) string {
-	if errors.Is(err, context.DeadlineExceeded) {
-		return "timeout"
-	}
-	text := strings.ToLower(err.Error())
-	if strings.Contains(text, "deadline exceeded") {
-		return "timeout"
-	}
-	if strings.Contains(text, "no such host") {
-		return "dns"
+func checkmany(input []string, opt options, probe alive.Probe) []alive.Result {
+	if opt.progress {
+		defer wipe()
+	}
+	if opt.progress || opt.feed != nil {
+		opt.Each = func(item alive.Result, done, total int) {
+			if opt.progress {
+				fmt.Printf("\rchecked %d/%d...", done, total)
+			}
+			if opt.feed != nil {
+				opt.feed <- item
+			}
+		}
 	}
-	if strings.Contains(text, "connection refused") {
-		return "refused"
+	opt.Batch = func(part, parts, done, total int) {
+		if opt.progress {
+			wipe()
+		}
+		fmt.Fprintf(os.Stderr, "batch %d/%d done (%d/%d targets)\n", part, parts, done, total)
 	}
-	if strings.Contains(text, "certificate") {
-		return "tls"
+	ctx := opt.ctx
+	if ctx == nil {
+		ctx = context.Background()
 	}
-	return "error"
+	return alive.New(alive.WithOptions(opt.Options), alive.WithProbe(probe)).CheckMany(ctx, input)
 }
 
-func render(rows []row) string {
-	if len(rows) == 0 {
-		return "no targets\n"
-	}
-	var b strings.Builder
-	fmt.Fprintln(&b, "target\tstate\tcode\tlatency\tsize\tnote")
-	for _, item := range rows {
-		code := "-"
-		if item.code > 0 {
-			code = strconv.Itoa(item.code)
-		}
-		latency := "-"
-		if item.span > 0 {
-			latency = item.span.Round(time.Millisecond).String()
-		}
-		size := "-"
-		if item.size > 0 {
-			size = strconv.FormatInt(item.size, 10)
-		}
-		note := "-"
-		if item.issue != "" {
-			note = item.issue
-		}
-		fmt.Fprintf(&b, "%s\t%s\t%s\t%s\t%s\t%s\n", item.target, item.state, code, latency, size, note)
-	}
-	return b.String()
+func wipe() {
+	fmt.Print("\r\x1b[K")
 }
 
 func printhelp() {
 	fmt.Println("alive")
 	fmt.Println("")
 	fmt.Println("usage:")
-	fmt.Println("  alive check <url> [url...] [timeoutms]")
-	fmt.Println("  alive file <path> [timeoutms]")
-	fmt.Println("  alive serve [port] [timeoutms]")
+	fmt.Println("  alive check <url> [url...] [timeout]   (- reads urls from stdin)")
+	fmt.Println("  alive file <path> [path...] [timeout]  (globs like 'checks/*.txt', - reads stdin)")
+	fmt.Println("  alive tcp <host:port> [host:port...] [timeout]")
+	fmt.Println("  alive dns <host> [host...] [timeout]")
+	fmt.Println("  alive run [--config alive.yaml]         (targets and flags from a config file)")
+	fmt.Println("  alive serve [port] [timeout]")
+	fmt.Println("  alive tui <path> [timeout]")
+	fmt.Println("")
+	fmt.Println("timeouts are milliseconds (2500) or durations (2.5s)")
+	fmt.Println("exit codes: 0 all up, 1 worst is warn, 2 something down or invalid")
+	fmt.Println("every flag can also be set as ALIVE_<NAME>, e.g. ALIVE_TIMEOUT=2s; flags win, even repeatable ones replace the env value")
+	fmt.Println("")
+	fmt.Println("flags:")
+	fmt.Println("  --preserve-order    keep targets in input order instead of sorting")
+	fmt.Println("  --normalize         lowercase scheme and host, drop default ports and add / to an empty path before dedup")
+	fmt.Println("  --auto-scheme       check targets without a scheme over https://, e.g. example.com")
+	fmt.Println("  --http-fallback     with --auto-scheme, retry over http:// when https cannot connect")
+	fmt.Println("  --strict-url        mark urls with credentials, a fragment or control characters invalid")
+	fmt.Println("  --shuffle           check targets in random order to spread load across hosts (output order is kept)")
+	fmt.Println("  --seed N            with --shuffle, use this seed for a repeatable order")
+	fmt.Println("  --sort KEY[:desc]   order output by latency, status, target or state")
+	fmt.Println("  --interval 10s      repeat checks every interval until ctrl-c (tui refresh, default 5s)")
+	fmt.Println("  --count N           stop after N cycles of --interval")
+	fmt.Println("  --workers N         concurrent checks, default 8 (shared across requests in serve)")
+	fmt.Println("  --timeout 2s        per-check timeout, duration or milliseconds, default 3500")
+	fmt.Println("  --connect-timeout 1s  limit tcp connect and tls handshake separately")
+	fmt.Println("  --rate N            start at most N checks per second across all workers")
+	fmt.Println("  --per-host N        run at most N checks against the same host at once")
+	fmt.Println("  --polite            wait --polite-delay between consecutive checks of the same host")
+	fmt.Println("  --polite-delay 1s   gap between checks of one host in --polite mode, default 1s")
+	fmt.Println("  --jitter D          wait a random 0..D before each check to spread load")
+	fmt.Println("  --no-keepalive      open a fresh connection for every check")
+	fmt.Println("  --batch-size N      check targets in chunks of N")
+	fmt.Println("  --batch-pause 2s    pause between chunks")
+	fmt.Println("  --alpn h2           warn when tls targets negotiate another protocol")
+	fmt.Println("  --detail            show remote ip, negotiated alpn, tls version and cert expiry columns")
+	fmt.Println("  --retries N         retry failed checks up to N times with backoff")
+	fmt.Println("  --retry-on LIST     what to retry: timeout,5xx,429,dns,refused,reset,eof,unreachable,tls,error (default timeout,5xx)")
+	fmt.Println("  --no-redirect       report 3xx responses as-is with their location")
+	fmt.Println("  --max-redirects N   warn when a redirect chain is longer than N, default 10")
+	fmt.Println("  --header \"K: V\"     add a request header, repeatable")
+	fmt.Println("  --user-agent UA     user-agent header to send (default alive/1, empty sends none)")
+	fmt.Println("  --cookie NAME=VALUE send this cookie on the first request (repeatable)")
+	fmt.Println("  --body TEXT         send TEXT as the request body (POST, PUT, PATCH or DELETE)")
+	fmt.Println("  --body-file PATH    send the contents of PATH as the request body")
+	fmt.Println("  --basic-auth u:p    send basic auth, overrides user:pass@ in the url")
+	fmt.Println("  --resolve H:P:IP    dial IP for host H port P, keeping Host and SNI (repeatable)")
+	fmt.Println("  --ipv4, --ipv6      only connect over that address family")
+	fmt.Println("  --proxy URL         send checks through this proxy (default: HTTP_PROXY/HTTPS_PROXY, not used by serve)")
+	fmt.Println("  --client-cert PATH  present this pem client certificate (needs --client-key; pairs with --insecure)")
+	fmt.Println("  --client-key PATH   private key for --client-cert")
+	fmt.Println("  --pin-sha256 B64    mark down unless the leaf cert's sha256 matches (repeatable for rotation)")
+	fmt.Println("  --insecure          skip tls verification (dangerous, rows are marked)")
+	fmt.Println("  --cert-warn-days N  warn when the tls certificate expires within N days")
+	fmt.Println("  --method M          request method: GET, HEAD, OPTIONS, POST, PUT, PATCH or DELETE (HEAD falls back to GET on 405)")
+	fmt.Println("  --all-ips           check every resolved address of each host")
+	fmt.Println("  --expect-body TEXT  warn when the body does not contain TEXT")
+	fmt.Println("  --slow-at D         warn when an otherwise up check takes longer than D (ms or duration)")
+	fmt.Println("  --warn-at CODE      status codes at or above CODE are warn (default 400)")
+	fmt.Println("  --warn-on LIST      report these errors as warn instead of down, e.g. tls,refused,body-timeout")
+	fmt.Println("  --expect CODES      comma list of status codes that count as up, e.g. 200,204")
+	fmt.Println("  --expect-regex RE   warn when the body does not match RE (body read up to --max-body)")
+	fmt.Println("  --measure-body      read bodies to report the real size instead of content-length (gzip is decoded)")
+	fmt.Println("  --max-body N        read at most N body bytes per response, default 1048576, 0 for no cap")
+	fmt.Println("  --max-total-bytes N read bodies, stopping once N bytes are read in a run")
+	fmt.Println("  --assert EXPR       warn when an expression does not hold, see readme")
+	fmt.Println("  --fail-on STATES    states that fail the run, default warn,down,invalid (none to disable)")
+	fmt.Println("  --exit-always-zero  exit 0 whatever the results")
+	fmt.Println("  --filter-tag LIST   only check file lines tagged with one of these @tags, e.g. prod,staging")
+	fmt.Println("  --only STATES       print only rows in these states, e.g. down,warn")
+	fmt.Println("  --group-by-host     add per-host up/warn/down counts and worst latency after the table")
+	fmt.Println("  --stream            print each row as soon as its check finishes (completion order)")
+	fmt.Println("  --out PATH          also write results to PATH (.json and .csv pick the format)")
+	fmt.Println("  --out-only          write results to --out without printing them")
+	fmt.Println("  --history PATH      append one json line per target per run to PATH")
+	fmt.Println("  --baseline PATH     compare states with a previous --json run (new, recovered, broke, same)")
+	fmt.Println("  --only-changes      with --baseline, show only targets whose state changed")
+	fmt.Println("  --statsd HOST:PORT  send per-target up, code and latency metrics over udp after each run")
+	fmt.Println("  --webhook URL       with --interval or serve --targets, post json when a target changes state")
+	fmt.Println("  --percentiles       with --interval, print p50/p95/p99/max per target after every cycle")
+	fmt.Println("  --quiet             print nothing when every target is up, otherwise only failing rows and the summary")
+	fmt.Println("  --summary           append an up/warn/down/invalid count line to the table")
+	fmt.Println("  --summary-only      print only the count line")
+	fmt.Println("  --no-progress       hide the checked N/M line shown while a terminal run is in progress")
+	fmt.Println("  --no-color          disable colored states (also NO_COLOR, off when piped)")
+	fmt.Println("  --http1, --http2    only speak that http version (http2 on plain http uses h2c)")
+	fmt.Println("  --show-redirects    show how many redirects each target followed")
+	fmt.Println("  --show-proto        show the http protocol version each target answered with")
+	fmt.Println("  --cert-info         print subject, issuer, sans and validity of each https target's leaf cert")
+	fmt.Println("  --trace             show remote ip plus dns, connect, tls handshake and first byte timings")
+	fmt.Println("  --allow-host HOST   serve: only check this host (.example.com matches subdomains, repeatable)")
+	fmt.Println("  --allow-cidr CIDR   serve: only check targets resolving inside CIDR (repeatable)")
+	fmt.Println("  --allow-private     serve: allow /check on loopback, private and link-local addresses")
+	fmt.Println("  --auth-token TOKEN  serve: require Authorization: Bearer TOKEN on /check, /events and /metrics")
+	fmt.Println("  --cors-origin LIST  serve: allow these browser origins (or *) to call /check")
+	fmt.Println("  --targets PATH      serve: check targets from a file in the background every --interval (30s)")
+	fmt.Println("  --format json       print results as json, csv or digest instead of a table")
+	fmt.Println("  --json              shorthand for --format json")
+	fmt.Println("  --json-pretty       indent json output")
+	fmt.Println("  --digest            print a one-line digest of failures only")
 }
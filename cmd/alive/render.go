@@ -0,0 +1,454 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keypad/alive/pkg/alive"
+)
+
+func render(rows []alive.Result, opt options) string {
+	if len(rows) == 0 {
+		return "no targets\n"
+	}
+	var b strings.Builder
+	head := columns(rows, opt)
+	fmt.Fprintln(&b, strings.Join(head, "\t"))
+	for _, item := range rows {
+		fmt.Fprintln(&b, line(item, head, opt))
+	}
+	return b.String()
+}
+
+func line(item alive.Result, head []string, opt options) string {
+	cells := make([]string, len(head))
+	for i, name := range head {
+		cells[i] = alive.Field(item, name)
+	}
+	if opt.color {
+		cells[1] = paint(cells[1])
+	}
+	return strings.Join(cells, "\t")
+}
+
+func rendercsv(rows []alive.Result, opt options) string {
+	var b strings.Builder
+	out := csv.NewWriter(&b)
+	head := columns(rows, opt)
+	out.Write(head)
+	for _, item := range rows {
+		cells := make([]string, len(head))
+		for i, name := range head {
+			switch {
+			case name == "latency" && item.Latency > 0:
+				cells[i] = strconv.FormatInt(item.Latency.Milliseconds(), 10)
+			case name == "latency":
+				cells[i] = ""
+			default:
+				if text := alive.Field(item, name); text != "-" {
+					cells[i] = text
+				}
+			}
+		}
+		out.Write(cells)
+	}
+	out.Flush()
+	return b.String()
+}
+
+func columns(rows []alive.Result, opt options) []string {
+	head := []string{"target", "state", "code", "latency", "size"}
+	if opt.stream {
+		head = append(head, "final")
+	}
+	for _, item := range rows {
+		if item.Final != "" && !opt.stream {
+			head = append(head, "final")
+			break
+		}
+	}
+	for _, item := range rows {
+		if item.Tag != "" || (opt.stream && len(opt.Tags) > 0) {
+			head = append(head, "tag")
+			break
+		}
+	}
+	if opt.Retries > 0 {
+		head = append(head, "attempts")
+	}
+	if opt.AllIPs || opt.Trace || opt.detail {
+		head = append(head, "ip")
+	}
+	if opt.proto || opt.Trace {
+		head = append(head, "proto")
+	}
+	if opt.hops || opt.Trace {
+		head = append(head, "redirects")
+	}
+	if opt.detail {
+		head = append(head, "alpn", "tls")
+	}
+	if opt.detail || opt.CertWarnDays > 0 {
+		head = append(head, "expiry")
+	}
+	if opt.Trace {
+		head = append(head, "dns", "connect", "handshake", "ttfb")
+	}
+	if opt.Baseline != nil {
+		head = append(head, "change")
+	}
+	return append(head, "note")
+}
+
+type record struct {
+	Run      *time.Time `json:"run,omitempty"`
+	Target   string     `json:"target"`
+	State    string     `json:"state"`
+	Code     *int       `json:"code"`
+	Latency  *int64     `json:"latency_ms"`
+	Size     *int64     `json:"size"`
+	Note     *string    `json:"note"`
+	ALPN     string     `json:"alpn,omitempty"`
+	TLS      string     `json:"tls,omitempty"`
+	IP       string     `json:"remote_ip,omitempty"`
+	Proto    string     `json:"proto,omitempty"`
+	Hops     *int       `json:"redirects,omitempty"`
+	Attempts int        `json:"attempts,omitempty"`
+	Final    string     `json:"final_url,omitempty"`
+	Expiry   *time.Time `json:"cert_expiry,omitempty"`
+	DNS      *int64     `json:"dns_ms,omitempty"`
+	Connect  *int64     `json:"connect_ms,omitempty"`
+	TLSTime  *int64     `json:"tls_ms,omitempty"`
+	TTFB     *int64     `json:"ttfb_ms,omitempty"`
+	Cert     *leaf      `json:"cert,omitempty"`
+	Change   string     `json:"change,omitempty"`
+	Tag      string     `json:"tag,omitempty"`
+}
+
+type leaf struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	SANs      []string  `json:"sans"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+func renderjson(rows []alive.Result, opt options) string {
+	list := records(rows, opt)
+	var data []byte
+	if opt.pretty {
+		data, _ = json.MarshalIndent(list, "", "  ")
+	} else {
+		data, _ = json.Marshal(list)
+	}
+	return string(data) + "\n"
+}
+
+func records(rows []alive.Result, opt options) []record {
+	list := make([]record, 0, len(rows))
+	for _, item := range rows {
+		rec := record{Target: item.Target, State: item.State, ALPN: item.ALPN, TLS: item.TLS, IP: item.IP, Proto: item.Proto, Attempts: item.Attempts, Final: item.Final, Change: item.Change, Tag: item.Tag}
+		if item.Code > 0 {
+			rec.Code = &item.Code
+		}
+		if item.Code > 0 && (opt.hops || opt.Trace) {
+			rec.Hops = &item.Redirects
+		}
+		if item.Latency > 0 {
+			ms := item.Latency.Milliseconds()
+			rec.Latency = &ms
+		}
+		if item.Size > 0 {
+			rec.Size = &item.Size
+		}
+		if item.Note != "" {
+			rec.Note = &item.Note
+		}
+		if !item.Expiry.IsZero() {
+			rec.Expiry = &item.Expiry
+		}
+		if opt.certinfo && item.Cert != nil {
+			rec.Cert = &leaf{Subject: item.Cert.Subject.String(), Issuer: item.Cert.Issuer.String(), SANs: sans(item.Cert), NotBefore: item.Cert.NotBefore, NotAfter: item.Cert.NotAfter}
+		}
+		if opt.Trace {
+			rec.DNS = millis(item.DNS)
+			rec.Connect = millis(item.Connect)
+			rec.TLSTime = millis(item.Handshake)
+			rec.TTFB = millis(item.TTFB)
+		}
+		list = append(list, rec)
+	}
+	return list
+}
+
+func renderdigest(rows []alive.Result) string {
+	groups := map[string][]string{}
+	for _, item := range rows {
+		if item.State == "up" {
+			continue
+		}
+		why := item.Note
+		if why == "" && item.Code > 0 {
+			why = strconv.Itoa(item.Code)
+		}
+		entry := item.Target
+		if why != "" {
+			entry += " (" + why + ")"
+		}
+		groups[item.State] = append(groups[item.State], entry)
+	}
+	if len(groups) == 0 {
+		return fmt.Sprintf("all up (%d targets)\n", len(rows))
+	}
+	parts := []string{}
+	for _, state := range []string{"down", "invalid", "warn"} {
+		if list := groups[state]; len(list) > 0 {
+			parts = append(parts, strings.ToUpper(state)+": "+strings.Join(list, ", "))
+		}
+	}
+	return strings.Join(parts, "; ") + "\n"
+}
+
+func hosts(rows []alive.Result) string {
+	type tally struct {
+		up, warn, down int
+		worst          time.Duration
+	}
+	groups := map[string]*tally{}
+	var names []string
+	for _, item := range rows {
+		name := "(invalid)"
+		if part, err := url.Parse(item.Target); err == nil && part.Host != "" && item.State != "invalid" {
+			name = part.Host
+		}
+		got, ok := groups[name]
+		if !ok {
+			got = &tally{}
+			groups[name] = got
+			names = append(names, name)
+		}
+		switch item.State {
+		case "up":
+			got.up++
+		case "warn":
+			got.warn++
+		case "down":
+			got.down++
+		}
+		got.worst = max(got.worst, item.Latency)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	fmt.Fprintln(&b, "host\tup\twarn\tdown\tworst")
+	for _, name := range names {
+		got := groups[name]
+		fmt.Fprintf(&b, "%s\t%d\t%d\t%d\t%s\n", name, got.up, got.warn, got.down, got.worst.Round(time.Millisecond))
+	}
+	return b.String()
+}
+
+type samples map[string][]time.Duration
+
+func (s samples) add(rows []alive.Result) {
+	for _, item := range rows {
+		if item.Latency > 0 {
+			s[item.Target] = append(s[item.Target], item.Latency)
+		}
+	}
+}
+
+func (s samples) table() string {
+	if len(s) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	fmt.Fprintln(&b, "# target\tsamples\tp50\tp95\tp99\tmax")
+	for _, name := range names {
+		list := slices.Clone(s[name])
+		slices.Sort(list)
+		fmt.Fprintf(&b, "# %s\t%d\t%s\t%s\t%s\t%s\n", name, len(list), pct(list, 50), pct(list, 95), pct(list, 99), list[len(list)-1].Round(time.Millisecond))
+	}
+	return b.String()
+}
+
+func pct(sorted []time.Duration, p int) time.Duration {
+	at := (len(sorted)*p + 99) / 100
+	return sorted[max(at-1, 0)].Round(time.Millisecond)
+}
+
+func certs(rows []alive.Result) string {
+	var b strings.Builder
+	for _, item := range rows {
+		fmt.Fprintf(&b, "# %s\n", item.Target)
+		leaf := item.Cert
+		switch {
+		case leaf != nil:
+		case !strings.HasPrefix(item.Target, "https://"):
+			b.WriteString("  skipped: not https\n")
+			continue
+		default:
+			b.WriteString("  no certificate\n")
+			continue
+		}
+		fmt.Fprintf(&b, "  subject  %s\n", leaf.Subject)
+		fmt.Fprintf(&b, "  issuer   %s\n", leaf.Issuer)
+		names := strings.Join(sans(leaf), ", ")
+		if names == "" {
+			names = "-"
+		}
+		fmt.Fprintf(&b, "  sans     %s\n", names)
+		fmt.Fprintf(&b, "  valid    %s .. %s\n", leaf.NotBefore.UTC().Format(time.RFC3339), leaf.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return b.String()
+}
+
+func sans(leaf *x509.Certificate) []string {
+	list := slices.Clone(leaf.DNSNames)
+	for _, ip := range leaf.IPAddresses {
+		list = append(list, ip.String())
+	}
+	list = append(list, leaf.EmailAddresses...)
+	for _, uri := range leaf.URIs {
+		list = append(list, uri.String())
+	}
+	return list
+}
+
+func summary(rows []alive.Result) string {
+	count := map[string]int{}
+	var total time.Duration
+	done := 0
+	for _, item := range rows {
+		count[item.State]++
+		if item.Code > 0 {
+			total += item.Latency
+			done++
+		}
+	}
+	line := fmt.Sprintf("%d up, %d warn, %d down, %d invalid", count["up"], count["warn"], count["down"], count["invalid"])
+	if done > 0 {
+		line += fmt.Sprintf(" (avg %s)", (total / time.Duration(done)).Round(time.Millisecond))
+	}
+	return line + "\n"
+}
+
+func bytag(rows []alive.Result) string {
+	split := map[string][]alive.Result{}
+	for _, item := range rows {
+		if item.Tag != "" {
+			split[item.Tag] = append(split[item.Tag], item)
+		}
+	}
+	text := ""
+	for _, tag := range slices.Sorted(maps.Keys(split)) {
+		text += "@" + tag + ": " + summary(split[tag])
+	}
+	return text
+}
+
+func millis(span time.Duration) *int64 {
+	ms := span.Milliseconds()
+	return &ms
+}
+
+func arrange(rows []alive.Result, key string, desc bool) []alive.Result {
+	list := slices.Clone(rows)
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if desc {
+			a, b = b, a
+		}
+		switch key {
+		case "latency":
+			return a.Latency < b.Latency
+		case "status":
+			return a.Code < b.Code
+		case "state":
+			return alive.Rank(a.State) < alive.Rank(b.State)
+		}
+		return a.Target < b.Target
+	})
+	return list
+}
+
+func output(rows []alive.Result, opt options) string {
+	if opt.quiet && allup(rows) {
+		return ""
+	}
+	if opt.quiet && opt.format == "table" {
+		opt.summary = true
+	}
+	shown := rows
+	if len(opt.only) > 0 || opt.changes {
+		shown = slices.DeleteFunc(slices.Clone(rows), func(item alive.Result) bool { return !visible(item, opt) })
+	}
+	if opt.order != "" {
+		shown = arrange(shown, opt.order, opt.desc)
+	}
+	switch opt.format {
+	case "json":
+		return renderjson(shown, opt)
+	case "csv":
+		return rendercsv(shown, opt)
+	case "digest":
+		return renderdigest(rows)
+	}
+	if opt.brief {
+		return summary(rows) + bytag(rows)
+	}
+	table := render(shown, opt)
+	if len(shown) == 0 && len(rows) > 0 {
+		table = "no matching targets\n"
+	}
+	return table + trailer(rows, shown, opt)
+}
+
+func allup(rows []alive.Result) bool {
+	return !slices.ContainsFunc(rows, func(item alive.Result) bool { return item.State != "up" })
+}
+
+func visible(item alive.Result, opt options) bool {
+	if len(opt.only) > 0 && !opt.only[item.State] {
+		return false
+	}
+	return !opt.changes || item.Change != "same"
+}
+
+func trailer(rows, shown []alive.Result, opt options) string {
+	text := ""
+	if opt.group {
+		text += "\n" + hosts(rows)
+	}
+	if opt.certinfo {
+		text += "\n" + certs(shown)
+	}
+	if opt.summary {
+		text += summary(rows) + bytag(rows)
+	}
+	return text
+}
+
+func paint(state string) string {
+	code := "31"
+	switch state {
+	case "up":
+		code = "32"
+	case "warn":
+		code = "33"
+	}
+	return "\x1b[" + code + "m" + state + "\x1b[0m"
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/keypad/alive/pkg/alive"
+)
+
+type board struct {
+	mu    sync.Mutex
+	last  map[string]alive.Result
+	total int64
+	subs  map[chan alive.Result]struct{}
+}
+
+func runserve(args []string, opt options) error {
+	port := "4177"
+	if len(args) > 0 {
+		port = args[0]
+	}
+	if len(args) > 1 {
+		part, err := alive.ParseTimeout(args[1])
+		if err != nil {
+			return err
+		}
+		opt.Timeout = part
+	}
+	addr := ":" + port
+	opt.Slots = make(chan struct{}, opt.Workers)
+	opt.color = false
+	opt.progress = false
+	halt, quit := context.WithCancel(context.Background())
+	defer quit()
+	opt.ctx = halt
+	wall := &alive.Fence{Hosts: opt.hosts, CIDRs: opt.cidrs, Private: opt.private}
+	board := &board{last: map[string]alive.Result{}, subs: map[chan alive.Result]struct{}{}}
+	if opt.targets != "" {
+		urls, err := load(opt.targets)
+		if err != nil {
+			return err
+		}
+		if len(urls) == 0 {
+			return errors.New("no urls in targets file")
+		}
+		go watch(urls, opt, board)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "alive")
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "try:")
+		fmt.Fprintln(w, "  /check?url=https://example.com")
+		fmt.Fprintln(w, "  /check?url=https://example.com&url=https://go.dev")
+		fmt.Fprintln(w, "  /check?url=https://example.com&timeout=1200")
+		fmt.Fprintln(w, "  /check.json?url=https://example.com")
+		fmt.Fprintln(w, "  POST /check {\"urls\": [\"https://example.com\"], \"timeout\": 2000}")
+		fmt.Fprintln(w, "  /events   (server-sent events from --targets)")
+		fmt.Fprintln(w, "  /metrics  (prometheus, from --targets)")
+		fmt.Fprintln(w, "  /healthz")
+		fmt.Fprintln(w, "  /version")
+	})
+	mux.HandleFunc("/check", cors(opt.origins, guard(opt.token, func(w http.ResponseWriter, r *http.Request) {
+		used := opt
+		used.Fence = wall
+		used.ctx = r.Context()
+		if strings.Contains(r.Header.Get("Accept"), "application/json") {
+			used.format = "json"
+		}
+		answer(w, r, used)
+	})))
+	mux.HandleFunc("/check.json", cors(opt.origins, guard(opt.token, func(w http.ResponseWriter, r *http.Request) {
+		used := opt
+		used.Fence = wall
+		used.ctx = r.Context()
+		used.format = "json"
+		answer(w, r, used)
+	})))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if commit != "" {
+			fmt.Fprintf(w, "alive %s (%s)\n", version, commit)
+			return
+		}
+		fmt.Fprintf(w, "alive %s\n", version)
+	})
+	mux.HandleFunc("/events", guard(opt.token, func(w http.ResponseWriter, r *http.Request) {
+		flush, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		feed := board.subscribe()
+		defer board.unsubscribe(feed)
+		fmt.Fprint(w, ": connected\n\n")
+		flush.Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-halt.Done():
+				return
+			case item := <-feed:
+				data, _ := json.Marshal(records([]alive.Result{item}, opt)[0])
+				fmt.Fprintf(w, "data: %s\n\n", data)
+				flush.Flush()
+			}
+		}
+	}))
+	mux.HandleFunc("/metrics", guard(opt.token, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		fmt.Fprint(w, board.metrics())
+	}))
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 2 * time.Second,
+	}
+	fmt.Printf("alive serving on %s\n", addr)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	select {
+	case err := <-errs:
+		return err
+	case <-stop:
+	}
+	fmt.Println("shutting down")
+	quit()
+	ctx, cancel := context.WithTimeout(context.Background(), opt.Timeout+2*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+func guard(token string, next http.HandlerFunc) http.HandlerFunc {
+	if token == "" {
+		return next
+	}
+	want := []byte("Bearer " + token)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func cors(origins []string, next http.HandlerFunc) http.HandlerFunc {
+	if len(origins) == 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		switch {
+		case slices.Contains(origins, "*"):
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		case origin != "" && slices.Contains(origins, origin):
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept, Authorization")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next(w, r)
+	}
+}
+
+type batch struct {
+	URLs    []string        `json:"urls"`
+	Timeout json.RawMessage `json:"timeout"`
+}
+
+func answer(w http.ResponseWriter, r *http.Request, used options) {
+	var query []string
+	var raw string
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		query = r.URL.Query()["url"]
+		if len(query) == 0 {
+			if one := strings.TrimSpace(r.URL.Query().Get("target")); one != "" {
+				query = []string{one}
+			}
+		}
+		if len(query) == 0 {
+			http.Error(w, "missing url query", http.StatusBadRequest)
+			return
+		}
+		raw = strings.TrimSpace(r.URL.Query().Get("timeout"))
+	case http.MethodPost:
+		var body batch
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&body); err != nil {
+			http.Error(w, "invalid json body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(body.URLs) == 0 {
+			http.Error(w, `json body needs a non-empty "urls" list`, http.StatusBadRequest)
+			return
+		}
+		query = body.URLs
+		raw = strings.Trim(string(body.Timeout), `"`)
+		used.format = "json"
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if raw != "" && raw != "null" {
+		part, err := alive.ParseTimeout(raw)
+		if err != nil {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+		used.Timeout = part
+	}
+	rows := checkmany(query, used, alive.HTTP)
+	switch used.format {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	fmt.Fprint(w, output(rows, used))
+}
+
+func watch(urls []string, opt options, board *board) {
+	every := opt.every
+	if every == 0 {
+		every = 30 * time.Second
+	}
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		rows := checkmany(urls, opt, alive.HTTP)
+		if opt.ctx.Err() != nil {
+			return
+		}
+		board.record(rows)
+		board.publish(rows)
+		opt.webhook.observe(rows)
+		select {
+		case <-tick.C:
+		case <-opt.ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *board) record(rows []alive.Result) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, item := range rows {
+		b.last[item.Target] = item
+	}
+	b.total += int64(len(rows))
+}
+
+func (b *board) subscribe() chan alive.Result {
+	feed := make(chan alive.Result, 64)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[feed] = struct{}{}
+	return feed
+}
+
+func (b *board) unsubscribe(feed chan alive.Result) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subs, feed)
+}
+
+func (b *board) publish(rows []alive.Result) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for feed := range b.subs {
+		for _, item := range rows {
+			select {
+			case feed <- item:
+			default:
+			}
+		}
+	}
+}
+
+func (b *board) metrics() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	keys := make([]string, 0, len(b.last))
+	for key := range b.last {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var out strings.Builder
+	out.WriteString("# HELP alive_target_up Whether the last check of a target was up.\n")
+	out.WriteString("# TYPE alive_target_up gauge\n")
+	for _, key := range keys {
+		up := 0
+		if b.last[key].State == "up" {
+			up = 1
+		}
+		fmt.Fprintf(&out, "alive_target_up{target=\"%s\"} %d\n", label(key), up)
+	}
+	out.WriteString("# HELP alive_latency_seconds Latency of the last check of a target.\n")
+	out.WriteString("# TYPE alive_latency_seconds gauge\n")
+	for _, key := range keys {
+		fmt.Fprintf(&out, "alive_latency_seconds{target=\"%s\"} %s\n", label(key), strconv.FormatFloat(b.last[key].Latency.Seconds(), 'f', -1, 64))
+	}
+	out.WriteString("# HELP alive_status_code HTTP status code of the last check of a target, 0 when none.\n")
+	out.WriteString("# TYPE alive_status_code gauge\n")
+	for _, key := range keys {
+		fmt.Fprintf(&out, "alive_status_code{target=\"%s\"} %d\n", label(key), b.last[key].Code)
+	}
+	out.WriteString("# HELP alive_checks_total Background checks performed since the server started.\n")
+	out.WriteString("# TYPE alive_checks_total counter\n")
+	fmt.Fprintf(&out, "alive_checks_total %d\n", b.total)
+	return out.String()
+}
+
+func label(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(text)
+}
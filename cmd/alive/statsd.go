@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/keypad/alive/pkg/alive"
+)
+
+type statsd struct {
+	addr string
+}
+
+func (s *statsd) push(rows []alive.Result) {
+	if s == nil || len(rows) == 0 {
+		return
+	}
+	conn, err := net.Dial("udp", s.addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "statsd:", err)
+		return
+	}
+	defer conn.Close()
+	var packet strings.Builder
+	for _, item := range rows {
+		name := "alive." + metric(item.Target)
+		up := 0
+		if item.State == "up" {
+			up = 1
+		}
+		lines := fmt.Sprintf("%s.up:%d|g\n%s.code:%d|g\n", name, up, name, item.Code)
+		if item.Latency > 0 {
+			lines += fmt.Sprintf("%s.latency:%d|ms\n", name, item.Latency.Milliseconds())
+		}
+		if packet.Len() > 0 && packet.Len()+len(lines) > 1400 {
+			s.send(conn, packet.String())
+			packet.Reset()
+		}
+		packet.WriteString(lines)
+	}
+	s.send(conn, packet.String())
+}
+
+func (s *statsd) send(conn net.Conn, text string) {
+	if _, err := conn.Write([]byte(strings.TrimSuffix(text, "\n"))); err != nil {
+		fmt.Fprintln(os.Stderr, "statsd:", err)
+	}
+}
+
+func metric(target string) string {
+	if _, rest, ok := strings.Cut(target, "://"); ok {
+		target = rest
+	}
+	target = strings.TrimSuffix(strings.ToLower(target), "/")
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, target)
+}
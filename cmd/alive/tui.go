@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/keypad/alive/pkg/alive"
+	"golang.org/x/term"
+)
+
+var orders = []string{"target", "state", "latency", "code"}
+
+type screen struct {
+	path   string
+	rows   []alive.Result
+	order  string
+	filter string
+	stamp  time.Time
+	busy   bool
+	color  bool
+	typing bool
+	input  string
+	height int
+}
+
+func runtui(args []string, opt options) error {
+	if len(args) == 0 {
+		return errors.New("missing file path")
+	}
+	path := args[0]
+	opt.progress = false
+	if len(args) > 1 {
+		part, err := alive.ParseTimeout(args[1])
+		if err != nil {
+			return err
+		}
+		opt.Timeout = part
+	}
+	urls, err := load(path)
+	if err != nil {
+		return err
+	}
+	if len(urls) == 0 {
+		return errors.New("no urls in file")
+	}
+	in := int(os.Stdin.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("tui needs a terminal")
+	}
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(in, state)
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(stop)
+	keys := make(chan string)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- string(buf[:n])
+		}
+	}()
+	view := &screen{path: path, order: orders[0], color: opt.color}
+	every := opt.every
+	if every == 0 {
+		every = 5 * time.Second
+	}
+	fresh := make(chan []alive.Result, 1)
+	refresh := func() {
+		if view.busy {
+			return
+		}
+		view.busy = true
+		go func() {
+			fresh <- checkmany(urls, opt, alive.HTTP)
+		}()
+	}
+	refresh()
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		if _, height, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			view.height = height
+		}
+		fmt.Print(strings.ReplaceAll(view.draw(), "\n", "\r\n"))
+		select {
+		case rows := <-fresh:
+			view.rows = rows
+			view.stamp = time.Now()
+			view.busy = false
+		case <-tick.C:
+			refresh()
+		case <-stop:
+			return nil
+		case chunk, ok := <-keys:
+			if !ok {
+				keys = nil
+				continue
+			}
+			for _, key := range view.keys(chunk) {
+				switch key {
+				case 'q', 3, 4:
+					return nil
+				case 'r':
+					refresh()
+				default:
+					view.apply(key)
+				}
+			}
+		}
+	}
+}
+
+// keys returns the keypresses in one read from the terminal. While a filter
+// is being typed every key goes to apply, so only ctrl-c and ctrl-d come back.
+// Escape sequences such as arrow keys are dropped whole.
+func (s *screen) keys(chunk string) []rune {
+	if len(chunk) > 1 && chunk[0] == 27 {
+		return nil
+	}
+	var out []rune
+	for _, key := range chunk {
+		if s.typing && key != 3 && key != 4 {
+			s.apply(key)
+			continue
+		}
+		out = append(out, key)
+	}
+	return out
+}
+
+func (s *screen) apply(key rune) {
+	if s.typing {
+		switch key {
+		case '\r', '\n':
+			s.filter = strings.TrimSpace(s.input)
+			s.typing = false
+		case 27:
+			s.typing = false
+		case 127, 8:
+			if len(s.input) > 0 {
+				_, size := utf8.DecodeLastRuneInString(s.input)
+				s.input = s.input[:len(s.input)-size]
+			}
+		default:
+			if key >= ' ' {
+				s.input += string(key)
+			}
+		}
+		return
+	}
+	switch key {
+	case 's':
+		for i, item := range orders {
+			if item == s.order {
+				s.order = orders[(i+1)%len(orders)]
+				return
+			}
+		}
+		s.order = orders[0]
+	case 'f', '/':
+		s.typing = true
+		s.input = s.filter
+	case 27:
+		s.filter = ""
+	}
+}
+
+func (s *screen) draw() string {
+	list := make([]alive.Result, 0, len(s.rows))
+	for _, item := range s.rows {
+		if s.filter != "" && !strings.Contains(item.Target, s.filter) && item.State != s.filter {
+			continue
+		}
+		list = append(list, item)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		switch s.order {
+		case "state":
+			return alive.Rank(a.State) > alive.Rank(b.State)
+		case "latency":
+			return a.Latency > b.Latency
+		case "code":
+			return a.Code > b.Code
+		}
+		return a.Target < b.Target
+	})
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(&b, "alive tui  %s  sort=%s", s.path, s.order)
+	if s.filter != "" {
+		fmt.Fprintf(&b, "  filter=%s", s.filter)
+	}
+	if s.busy {
+		b.WriteString("  checking...\n\n")
+	} else {
+		fmt.Fprintf(&b, "  updated %s\n\n", s.stamp.Format("15:04:05"))
+	}
+	shown := list
+	if room := s.height - 6; s.height > 0 && len(shown) > max(room, 1) {
+		shown = shown[:max(room, 1)]
+	}
+	tab := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tab, "target\tstate\tcode\tlatency\tnote")
+	for _, item := range shown {
+		code := "-"
+		if item.Code > 0 {
+			code = strconv.Itoa(item.Code)
+		}
+		latency := "-"
+		if item.Latency > 0 {
+			latency = item.Latency.Round(time.Millisecond).String()
+		}
+		state := item.State
+		if s.color {
+			state = paint(state)
+		}
+		fmt.Fprintf(tab, "%s\t%s\t%s\t%s\t%s\n", item.Target, state, code, latency, alive.Field(item, "note"))
+	}
+	tab.Flush()
+	fmt.Fprintf(&b, "\n%d shown, %d total", len(shown), len(s.rows))
+	if len(shown) < len(list) {
+		fmt.Fprintf(&b, ", %d match below the fold", len(list)-len(shown))
+	}
+	if s.typing {
+		fmt.Fprintf(&b, "\nfilter: %s\x1b[?25h", s.input)
+		return b.String()
+	}
+	b.WriteString("\nkeys: s sort, f filter, esc clear filter, r refresh, q quit\x1b[?25l")
+	return b.String()
+}
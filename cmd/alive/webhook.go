@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/keypad/alive/pkg/alive"
+)
+
+type event struct {
+	Target string    `json:"target"`
+	Old    string    `json:"old_state"`
+	New    string    `json:"new_state"`
+	Code   int       `json:"code,omitempty"`
+	At     time.Time `json:"timestamp"`
+}
+
+type notifier struct {
+	url  string
+	seen map[string]string
+	wait sync.WaitGroup
+}
+
+func (n *notifier) observe(rows []alive.Result) {
+	if n == nil {
+		return
+	}
+	if n.seen == nil {
+		n.seen = map[string]string{}
+	}
+	at := time.Now().UTC()
+	for _, item := range rows {
+		was, ok := n.seen[item.Target]
+		n.seen[item.Target] = item.State
+		if !ok || was == item.State {
+			continue
+		}
+		n.wait.Add(1)
+		go n.send(event{Target: item.Target, Old: was, New: item.State, Code: item.Code, At: at})
+	}
+}
+
+func (n *notifier) flush() {
+	if n != nil {
+		n.wait.Wait()
+	}
+}
+
+func (n *notifier) send(ev event) {
+	defer n.wait.Done()
+	data, _ := json.Marshal(ev)
+	ctx, stop := context.WithTimeout(context.Background(), 10*time.Second)
+	defer stop()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(data))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "webhook:", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "webhook:", err)
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		fmt.Fprintf(os.Stderr, "webhook: %s answered %d\n", n.url, res.StatusCode)
+	}
+}
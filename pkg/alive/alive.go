@@ -0,0 +1,640 @@
+// Package alive checks whether urls, tcp ports and hostnames answer.
+package alive
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of one check.
+type Result struct {
+	Target    string
+	State     string
+	Code      int
+	Latency   time.Duration
+	Size      int64
+	Note      string
+	ALPN      string
+	TLS       string
+	IP        string
+	Header    http.Header
+	Attempts  int
+	Final     string
+	Expiry    time.Time
+	DNS       time.Duration
+	Connect   time.Duration
+	Handshake time.Duration
+	TTFB      time.Duration
+	Change    string
+	Proto     string
+	Redirects int
+	Cert      *x509.Certificate
+	Tag       string
+}
+
+// Options configures a run. Start from Defaults and change what you need.
+// A negative MaxRedirects allows no redirects at all, a negative MaxBody
+// reads bodies without a cap and a UserAgent of "-" sends no User-Agent.
+type Options struct {
+	Timeout        time.Duration
+	ConnectTimeout time.Duration
+	Workers        int
+	Slots          chan struct{}
+	Rate           float64
+	PerHost        int
+	Jitter         time.Duration
+	Polite         time.Duration
+	BatchSize      int
+	BatchPause     time.Duration
+	AllIPs         bool
+	KeepOrder      bool
+	Normalize      bool
+	AutoScheme     bool
+	StrictURL      bool
+	HTTPFallback   bool
+	Tags           map[string]bool
+	Shuffle        bool
+	Seed           uint64
+	MaxTotalBytes  int64
+	Method         string
+	Retries        int
+	RetryOn        map[string]bool
+	NoRedirect     bool
+	MaxRedirects   int
+	Headers        http.Header
+	BasicAuth      string
+	UserAgent      string
+	Cookies        []*http.Cookie
+	Body           []byte
+	Insecure       bool
+	ClientCert     *tls.Certificate
+	Proxy          *url.URL
+	Resolve        map[string]string
+	Family         string
+	HTTPVersion    string
+	NoKeepAlive    bool
+	Fence          *Fence
+	Expect         map[int]bool
+	WarnAt         int
+	WarnOn         map[string]bool
+	ExpectBody     string
+	ExpectRegex    *regexp.Regexp
+	MaxBody        int64
+	MeasureBody    bool
+	ALPN           string
+	CertWarnDays   int
+	Pins           map[string]bool
+	SlowAt         time.Duration
+	Trace          bool
+	Assert         func(Result) (bool, error)
+	Baseline       map[string]string
+	Each           func(item Result, done, total int)
+	Batch          func(part, parts, done, total int)
+
+	ctx    context.Context
+	pin    string
+	tag    string
+	bare   map[string]bool
+	budget *budget
+	shared *http.Transport
+	tally  *tally
+}
+
+// Probe checks one target.
+type Probe func(item string, opt Options) Result
+
+// Defaults returns the Options the cli starts from.
+func Defaults() Options {
+	return Options{
+		Timeout:      3500 * time.Millisecond,
+		Workers:      8,
+		Method:       http.MethodGet,
+		MaxRedirects: 10,
+		UserAgent:    "alive/1",
+		WarnAt:       400,
+		MaxBody:      1 << 20,
+		RetryOn:      map[string]bool{"timeout": true, "5xx": true},
+	}
+}
+
+// Check runs one http check with default Options.
+func Check(target string, span time.Duration) Result {
+	return New(WithTimeout(span)).Check(target)
+}
+
+// CheckMany checks urls concurrently with default Options, sorted by target.
+// When ctx is done checks in flight are aborted, no new ones start and the
+// unfinished targets come back in the cancelled state.
+func CheckMany(ctx context.Context, urls []string, span time.Duration) []Result {
+	return New(WithTimeout(span)).CheckMany(ctx, urls)
+}
+
+func run(ctx context.Context, input []string, opt Options, probe Probe) []Result {
+	if opt.AutoScheme {
+		input, opt.bare = schemes(input, opt.Normalize)
+	}
+	if opt.Normalize {
+		input = normalize(input)
+	}
+	if len(opt.Tags) > 0 {
+		input = slices.DeleteFunc(slices.Clone(input), func(item string) bool {
+			_, used, _ := Tune(item, opt)
+			return !opt.Tags[used.tag]
+		})
+	}
+	jobs := expand(Clean(input, opt.KeepOrder), opt)
+	rows := make([]Result, len(jobs))
+	if opt.MaxTotalBytes > 0 {
+		opt.budget = &budget{limit: opt.MaxTotalBytes}
+	}
+	if !opt.AllIPs {
+		if tr := transport(opt); tr != nil {
+			defer tr.CloseIdleConnections()
+			opt.shared = tr
+		}
+	}
+	if len(jobs) == 0 {
+		return rows
+	}
+	if opt.Each != nil {
+		opt.tally = &tally{total: len(jobs), each: opt.Each}
+	}
+	var order []int
+	if opt.Shuffle {
+		seed := opt.Seed
+		if seed == 0 {
+			seed = rand.Uint64()
+		}
+		order = rand.New(rand.NewPCG(seed, seed)).Perm(len(jobs))
+		mixed := make([]job, len(jobs))
+		for i, at := range order {
+			mixed[i] = jobs[at]
+		}
+		jobs = mixed
+	}
+	size := len(jobs)
+	if opt.BatchSize > 0 {
+		size = opt.BatchSize
+	}
+	total := (len(jobs) + size - 1) / size
+	for start, part := 0, 1; start < len(jobs); start, part = start+size, part+1 {
+		end := min(start+size, len(jobs))
+		pool(ctx, jobs[start:end], rows[start:end], opt, probe)
+		if opt.BatchSize == 0 || ctx.Err() != nil {
+			continue
+		}
+		if opt.Batch != nil {
+			opt.Batch(part, total, end, len(jobs))
+		}
+		if end < len(jobs) && opt.BatchPause > 0 {
+			sleep(ctx, opt.BatchPause)
+		}
+	}
+	if order != nil {
+		mixed := rows
+		rows = make([]Result, len(mixed))
+		for i, at := range order {
+			rows[at] = mixed[i]
+		}
+	}
+	return rows
+}
+
+type job struct {
+	item string
+	pin  string
+}
+
+func pool(ctx context.Context, jobs []job, rows []Result, opt Options, probe Probe) {
+	count := len(jobs)
+	workers := opt.Workers
+	if count < workers {
+		workers = count
+	}
+	queue := make(chan int)
+	limits := &gates{size: opt.PerHost, open: map[string]chan struct{}{}, last: map[string]time.Time{}}
+	limits.delay = opt.Polite
+	var wait sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for index := range queue {
+				task := jobs[index]
+				item, used, err := Tune(task.item, opt)
+				if err != nil {
+					rows[index] = broken(task.item, err)
+					opt.tally.add(rows[index])
+					continue
+				}
+				used.pin = task.pin
+				used.ctx = ctx
+				if opt.Jitter > 0 {
+					sleep(ctx, rand.N(opt.Jitter))
+				}
+				if pause := limits.pace(item); pause > 0 {
+					sleep(ctx, pause)
+				}
+				if ctx.Err() != nil {
+					rows[index] = cancelled(item, used)
+					opt.tally.add(rows[index])
+					continue
+				}
+				gate := limits.take(item)
+				if opt.Slots != nil {
+					opt.Slots <- struct{}{}
+				}
+				rows[index] = probe(item, used)
+				if opt.HTTPFallback && opt.bare[item] && fallback(rows[index]) {
+					why := rows[index].Note
+					rows[index] = probe("http://"+strings.TrimPrefix(item, "https://"), used)
+					rows[index].Note = strings.TrimSpace(rows[index].Note + " (https " + why + ")")
+				}
+				if rows[index].Note == "cancelled" && ctx.Err() != nil {
+					rows[index].State, rows[index].Note = "cancelled", "in flight"
+				}
+				rows[index].Tag = used.tag
+				if rows[index].State == "down" && opt.WarnOn[rows[index].Note] {
+					rows[index].State = "warn"
+				}
+				if opt.Slots != nil {
+					<-opt.Slots
+				}
+				if gate != nil {
+					<-gate
+				}
+				if task.pin != "" {
+					rows[index].IP = task.pin
+				}
+				if opt.Baseline != nil {
+					rows[index].Change = diff(opt.Baseline, rows[index])
+				}
+				opt.tally.add(rows[index])
+			}
+		}()
+	}
+	var tick *time.Ticker
+	if opt.Rate > 0 {
+		gap := float64(time.Second) / opt.Rate
+		tick = time.NewTicker(time.Duration(min(max(gap, 1), math.MaxInt64/2)))
+		defer tick.Stop()
+	}
+	next := 0
+	for next < len(jobs) && ctx.Err() == nil {
+		if tick != nil && next > 0 {
+			select {
+			case <-tick.C:
+			case <-ctx.Done():
+				continue
+			}
+		}
+		select {
+		case queue <- next:
+			next++
+		case <-ctx.Done():
+		}
+	}
+	close(queue)
+	wait.Wait()
+	for ; next < len(jobs); next++ {
+		item, used, err := Tune(jobs[next].item, opt)
+		used.pin = jobs[next].pin
+		rows[next] = cancelled(item, used)
+		if err != nil {
+			rows[next] = broken(jobs[next].item, err)
+		}
+		opt.tally.add(rows[next])
+	}
+}
+
+func broken(line string, err error) Result {
+	head, rest, _ := strings.Cut(line, " ")
+	return Result{Target: strings.TrimSpace(strip(head) + " " + rest), State: "invalid", Note: err.Error()}
+}
+
+func cancelled(item string, opt Options) Result {
+	return Result{Target: strip(item), State: "cancelled", Note: "not started", IP: opt.pin, Tag: opt.tag}
+}
+
+// parent is the context probes run under, so a run that is cancelled aborts
+// the checks it already started.
+func parent(opt Options) context.Context {
+	if opt.ctx != nil {
+		return opt.ctx
+	}
+	return context.Background()
+}
+
+func sleep(ctx context.Context, span time.Duration) {
+	wait := time.NewTimer(span)
+	defer wait.Stop()
+	select {
+	case <-wait.C:
+	case <-ctx.Done():
+	}
+}
+
+type tally struct {
+	mu    sync.Mutex
+	done  int
+	total int
+	each  func(Result, int, int)
+}
+
+func (t *tally) add(item Result) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done++
+	t.each(item, t.done, t.total)
+}
+
+type gates struct {
+	size  int
+	delay time.Duration
+	mu    sync.Mutex
+	open  map[string]chan struct{}
+	last  map[string]time.Time
+}
+
+func (g *gates) take(item string) chan struct{} {
+	if g.size <= 0 {
+		return nil
+	}
+	host := origin(item)
+	g.mu.Lock()
+	gate, ok := g.open[host]
+	if !ok {
+		gate = make(chan struct{}, g.size)
+		g.open[host] = gate
+	}
+	g.mu.Unlock()
+	gate <- struct{}{}
+	return gate
+}
+
+func (g *gates) pace(item string) time.Duration {
+	if g.delay <= 0 {
+		return 0
+	}
+	host := origin(item)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	next := g.last[host].Add(g.delay)
+	if next.Before(now) {
+		next = now
+	}
+	g.last[host] = next
+	return next.Sub(now)
+}
+
+func origin(item string) string {
+	if part, err := url.Parse(item); err == nil && part.Host != "" {
+		return part.Host
+	}
+	return item
+}
+
+func expand(urls []string, opt Options) []job {
+	list := make([]job, 0, len(urls))
+	for _, item := range urls {
+		ips := []string(nil)
+		if opt.AllIPs {
+			ips = lookup(strings.Fields(item)[0], opt.Timeout)
+		}
+		if len(ips) == 0 {
+			list = append(list, job{item: item})
+			continue
+		}
+		for _, ip := range ips {
+			list = append(list, job{item: item, pin: ip})
+		}
+	}
+	return list
+}
+
+func lookup(item string, span time.Duration) []string {
+	if Valid(item) != nil {
+		return nil
+	}
+	part, err := url.Parse(item)
+	if err != nil {
+		return nil
+	}
+	host := part.Hostname()
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	ctx, stop := context.WithTimeout(context.Background(), span)
+	defer stop()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	list := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		list = append(list, addr.IP.String())
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Clean trims and dedups input, sorting it unless keep is set.
+func Clean(input []string, keep bool) []string {
+	set := map[string]struct{}{}
+	list := make([]string, 0, len(input))
+	for _, raw := range input {
+		item := strings.TrimSpace(raw)
+		if item == "" {
+			continue
+		}
+		if _, ok := set[item]; ok {
+			continue
+		}
+		set[item] = struct{}{}
+		list = append(list, item)
+	}
+	if !keep {
+		sort.Strings(list)
+	}
+	return list
+}
+
+func schemes(input []string, tidy bool) ([]string, map[string]bool) {
+	bare := map[string]bool{}
+	list := make([]string, 0, len(input))
+	for _, raw := range input {
+		item, extra, _ := strings.Cut(strings.TrimSpace(raw), " ")
+		if item == "" || strings.Contains(item, "://") {
+			list = append(list, raw)
+			continue
+		}
+		line := "https://" + item
+		if extra != "" {
+			line += " " + extra
+		}
+		if tidy {
+			line = normalize([]string{line})[0]
+		}
+		bare[strings.Fields(line)[0]] = true
+		list = append(list, line)
+	}
+	return list, bare
+}
+
+func fallback(item Result) bool {
+	if item.State != "down" {
+		return false
+	}
+	switch item.Note {
+	case "refused", "connect-timeout", "reset":
+		return true
+	}
+	return false
+}
+
+func normalize(input []string) []string {
+	list := make([]string, 0, len(input))
+	for _, raw := range input {
+		item, extra, _ := strings.Cut(strings.TrimSpace(raw), " ")
+		part, err := url.Parse(item)
+		if err != nil || part.Host == "" {
+			list = append(list, raw)
+			continue
+		}
+		part.Scheme = strings.ToLower(part.Scheme)
+		part.Host = strings.ToLower(part.Host)
+		switch {
+		case part.Scheme == "http" && part.Port() == "80", part.Scheme == "https" && part.Port() == "443":
+			part.Host = strings.TrimSuffix(part.Host, ":"+part.Port())
+		}
+		if part.Path == "" {
+			part.Path = "/"
+		}
+		item = part.String()
+		if extra != "" {
+			item += " " + extra
+		}
+		list = append(list, item)
+	}
+	return list
+}
+
+// Tune splits a target line into the target and the options its
+// timeout=, expect= and @tag overrides produce.
+func Tune(line string, opt Options) (string, Options, error) {
+	parts := strings.Fields(line)
+	if len(parts) == 0 {
+		return line, opt, nil
+	}
+	for _, part := range parts[1:] {
+		if tag, ok := strings.CutPrefix(part, "@"); ok {
+			if tag == "" || opt.tag != "" {
+				return "", opt, fmt.Errorf("tag %q must be a single @name per line", part)
+			}
+			opt.tag = tag
+			continue
+		}
+		key, raw, ok := strings.Cut(part, "=")
+		if !ok {
+			return "", opt, fmt.Errorf("option %q must look like key=value", part)
+		}
+		var err error
+		switch key {
+		case "timeout":
+			opt.Timeout, err = ParseTimeout(raw)
+		case "expect":
+			opt.Expect, err = ParseCodes(raw)
+		default:
+			return "", opt, fmt.Errorf("unknown option %q (want timeout or expect)", key)
+		}
+		if err != nil {
+			return "", opt, err
+		}
+	}
+	return parts[0], opt, nil
+}
+
+func diff(base map[string]string, item Result) string {
+	was, ok := base[item.Target]
+	switch {
+	case !ok:
+		return "new"
+	case Rank(item.State) > Rank(was):
+		return "broke"
+	case Rank(item.State) < Rank(was):
+		return "recovered"
+	}
+	return "same"
+}
+
+// Rank orders states from best to worst.
+func Rank(state string) int {
+	switch state {
+	case "up":
+		return 0
+	case "warn":
+		return 1
+	case "down":
+		return 2
+	}
+	return 3
+}
+
+// ParseTimeout reads milliseconds (2500) or a duration (2.5s).
+func ParseTimeout(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	span, err := time.ParseDuration(raw)
+	if err != nil {
+		count, err := strconv.Atoi(raw)
+		if err != nil {
+			return 0, errors.New("timeout must be a positive duration or milliseconds")
+		}
+		span = time.Duration(count) * time.Millisecond
+	}
+	if span <= 0 {
+		return 0, errors.New("timeout must be a positive duration or milliseconds")
+	}
+	if span > 120*time.Second {
+		return 0, errors.New("timeout too large")
+	}
+	return span, nil
+}
+
+// ParseCodes reads a comma list of status codes.
+func ParseCodes(raw string) (map[int]bool, error) {
+	set := map[int]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("unknown status code: %s", part)
+		}
+		set[code] = true
+	}
+	if len(set) == 0 {
+		return nil, errors.New("expect needs at least one status code")
+	}
+	return set, nil
+}
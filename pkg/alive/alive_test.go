@@ -0,0 +1,74 @@
+package alive
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDedup(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]int{}
+	stub := func(item string, opt Options) Result {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[item]++
+		return Result{Target: item, State: "up"}
+	}
+	input := []string{"http://a", " http://a ", "http://a\t", "", "  ", "http://b", "http://b", "\thttp://b"}
+	rows := New(WithProbe(stub)).CheckMany(context.Background(), input)
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if rows[0].Target != "http://a" || rows[1].Target != "http://b" {
+		t.Fatalf("got targets %q %q", rows[0].Target, rows[1].Target)
+	}
+	for _, item := range []string{"http://a", "http://b"} {
+		if seen[item] != 1 {
+			t.Errorf("%s checked %d times, want 1", item, seen[item])
+		}
+	}
+	if len(seen) != 2 {
+		t.Errorf("stub saw %v, want only http://a and http://b", seen)
+	}
+}
+
+func TestBadOption(t *testing.T) {
+	stub := func(item string, opt Options) Result {
+		return Result{Target: item, State: "up"}
+	}
+	rows := New(WithProbe(stub)).CheckMany(context.Background(), []string{"http://u:p@b bogus=1", "http://a timeout=2s"})
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if rows[0].Target != "http://a" || rows[0].State != "up" {
+		t.Errorf("got %+v, want http://a up", rows[0])
+	}
+	if rows[1].Target != "http://b bogus=1" || rows[1].State != "invalid" || !strings.Contains(rows[1].Note, "bogus") {
+		t.Errorf("got %+v, want an invalid row naming the bad option", rows[1])
+	}
+}
+
+func TestRateBounds(t *testing.T) {
+	stub := func(item string, opt Options) Result {
+		return Result{Target: item, State: "up"}
+	}
+	cases := []struct {
+		rate  float64
+		input []string
+	}{
+		{5e9, []string{"http://a", "http://b", "http://c"}},
+		{math.Inf(1), []string{"http://a", "http://b"}},
+		{1e-12, []string{"http://a"}},
+	}
+	for _, tc := range cases {
+		opt := Defaults()
+		opt.Rate = tc.rate
+		rows := New(WithOptions(opt), WithProbe(stub)).CheckMany(context.Background(), tc.input)
+		if len(rows) != len(tc.input) {
+			t.Errorf("rate %v: got %d rows, want %d", tc.rate, len(rows), len(tc.input))
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package alive
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type value struct {
+	kind string
+	num  float64
+	text string
+	flag bool
+}
+
+type expr func(item Result) (value, error)
+
+type parser struct {
+	toks []string
+	at   int
+}
+
+func parseassert(raw string) (expr, error) {
+	toks, err := lex(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return nil, errors.New("assert: empty expression")
+	}
+	p := &parser{toks: toks}
+	fn, kind, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.at < len(p.toks) {
+		return nil, fmt.Errorf("assert: unexpected %q", p.toks[p.at])
+	}
+	if kind != "bool" {
+		return nil, errors.New("assert: expression must be a comparison")
+	}
+	return fn, nil
+}
+
+// Assert compiles an expression such as "status==200 && latency<500ms"
+// for Options.Assert.
+func Assert(raw string) (func(Result) (bool, error), error) {
+	fn, err := parseassert(raw)
+	if err != nil {
+		return nil, err
+	}
+	return func(item Result) (bool, error) {
+		out, err := fn(item)
+		return out.flag, err
+	}, nil
+}
+
+func lex(raw string) ([]string, error) {
+	var toks []string
+	for i := 0; i < len(raw); {
+		ch := raw[i]
+		switch {
+		case ch == ' ' || ch == '\t':
+			i++
+		case ch == '\'' || ch == '"':
+			end := strings.IndexByte(raw[i+1:], ch)
+			if end < 0 {
+				return nil, errors.New("assert: unterminated string")
+			}
+			toks = append(toks, raw[i:i+end+2])
+			i += end + 2
+		case i+1 < len(raw) && pairs[raw[i:i+2]]:
+			toks = append(toks, raw[i:i+2])
+			i += 2
+		case strings.IndexByte("<>!(),", ch) >= 0:
+			toks = append(toks, string(ch))
+			i++
+		case word(ch):
+			end := i
+			for end < len(raw) && (word(raw[end]) || raw[end] == '.') {
+				end++
+			}
+			toks = append(toks, raw[i:end])
+			i = end
+		default:
+			return nil, fmt.Errorf("assert: unexpected %q", string(ch))
+		}
+	}
+	return toks, nil
+}
+
+var pairs = map[string]bool{"==": true, "!=": true, "<=": true, ">=": true, "&&": true, "||": true}
+
+func word(ch byte) bool {
+	return ch == '_' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+func (p *parser) peek() string {
+	if p.at < len(p.toks) {
+		return p.toks[p.at]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	tok := p.peek()
+	p.at++
+	return tok
+}
+
+func (p *parser) or() (expr, string, error) {
+	left, kind, err := p.and()
+	if err != nil {
+		return nil, "", err
+	}
+	for p.peek() == "||" {
+		p.next()
+		right, other, err := p.and()
+		if err != nil {
+			return nil, "", err
+		}
+		if kind != "bool" || other != "bool" {
+			return nil, "", errors.New("assert: && and || need comparisons")
+		}
+		left = logic(left, right, true)
+	}
+	return left, kind, nil
+}
+
+func (p *parser) and() (expr, string, error) {
+	left, kind, err := p.unary()
+	if err != nil {
+		return nil, "", err
+	}
+	for p.peek() == "&&" {
+		p.next()
+		right, other, err := p.unary()
+		if err != nil {
+			return nil, "", err
+		}
+		if kind != "bool" || other != "bool" {
+			return nil, "", errors.New("assert: && and || need comparisons")
+		}
+		left = logic(left, right, false)
+	}
+	return left, kind, nil
+}
+
+func (p *parser) unary() (expr, string, error) {
+	if p.peek() != "!" {
+		return p.compare()
+	}
+	p.next()
+	inner, kind, err := p.unary()
+	if err != nil {
+		return nil, "", err
+	}
+	if kind != "bool" {
+		return nil, "", errors.New("assert: ! needs a boolean")
+	}
+	return func(item Result) (value, error) {
+		got, err := inner(item)
+		if err != nil {
+			return value{}, err
+		}
+		return value{kind: "bool", flag: !got.flag}, nil
+	}, "bool", nil
+}
+
+func (p *parser) compare() (expr, string, error) {
+	left, kind, err := p.term()
+	if err != nil {
+		return nil, "", err
+	}
+	op := p.peek()
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return left, kind, nil
+	}
+	p.next()
+	right, other, err := p.term()
+	if err != nil {
+		return nil, "", err
+	}
+	if kind != other {
+		return nil, "", fmt.Errorf("assert: cannot compare %s with %s", kind, other)
+	}
+	if kind == "bool" && op != "==" && op != "!=" {
+		return nil, "", fmt.Errorf("assert: %s does not apply to booleans", op)
+	}
+	return func(item Result) (value, error) {
+		a, err := left(item)
+		if err != nil {
+			return value{}, err
+		}
+		b, err := right(item)
+		if err != nil {
+			return value{}, err
+		}
+		return versus(a, b, op), nil
+	}, "bool", nil
+}
+
+func (p *parser) term() (expr, string, error) {
+	tok := p.next()
+	switch {
+	case tok == "":
+		return nil, "", errors.New("assert: unexpected end of expression")
+	case tok == "(":
+		inner, kind, err := p.or()
+		if err != nil {
+			return nil, "", err
+		}
+		if p.next() != ")" {
+			return nil, "", errors.New("assert: missing )")
+		}
+		return inner, kind, nil
+	case tok[0] == '\'' || tok[0] == '"':
+		text := tok[1 : len(tok)-1]
+		return constant(value{kind: "text", text: text}), "text", nil
+	case tok[0] >= '0' && tok[0] <= '9':
+		num, err := number(tok)
+		if err != nil {
+			return nil, "", err
+		}
+		return constant(value{kind: "num", num: num}), "num", nil
+	case tok == "true" || tok == "false":
+		return constant(value{kind: "bool", flag: tok == "true"}), "bool", nil
+	case tok == "header":
+		if p.next() != "(" {
+			return nil, "", errors.New("assert: header needs a name, like header('x-cache')")
+		}
+		name := p.next()
+		if name == "" || name[0] != '\'' && name[0] != '"' {
+			return nil, "", errors.New("assert: header name must be quoted")
+		}
+		if p.next() != ")" {
+			return nil, "", errors.New("assert: missing )")
+		}
+		key := name[1 : len(name)-1]
+		return func(item Result) (value, error) {
+			return value{kind: "text", text: item.Header.Get(key)}, nil
+		}, "text", nil
+	}
+	fn, ok := fields[tok]
+	if !ok {
+		return nil, "", fmt.Errorf("assert: unknown field %q", tok)
+	}
+	return func(item Result) (value, error) {
+		return fn(item), nil
+	}, fn(Result{}).kind, nil
+}
+
+var fields = map[string]func(item Result) value{
+	"status": func(item Result) value { return value{kind: "num", num: float64(item.Code)} },
+	"latency": func(item Result) value {
+		return value{kind: "num", num: float64(item.Latency) / float64(time.Millisecond)}
+	},
+	"size":  func(item Result) value { return value{kind: "num", num: float64(item.Size)} },
+	"state": func(item Result) value { return value{kind: "text", text: item.State} },
+	"note":  func(item Result) value { return value{kind: "text", text: item.Note} },
+	"alpn":  func(item Result) value { return value{kind: "text", text: item.ALPN} },
+	"tls":   func(item Result) value { return value{kind: "text", text: item.TLS} },
+	"ip":    func(item Result) value { return value{kind: "text", text: item.IP} },
+	"proto": func(item Result) value { return value{kind: "text", text: item.Proto} },
+}
+
+func number(tok string) (float64, error) {
+	end := 0
+	for end < len(tok) && (tok[end] >= '0' && tok[end] <= '9' || tok[end] == '.') {
+		end++
+	}
+	if end == len(tok) {
+		num, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return 0, fmt.Errorf("assert: bad number %q", tok)
+		}
+		return num, nil
+	}
+	span, err := time.ParseDuration(tok)
+	if err != nil {
+		return 0, fmt.Errorf("assert: bad duration %q", tok)
+	}
+	return float64(span) / float64(time.Millisecond), nil
+}
+
+func constant(v value) expr {
+	return func(Result) (value, error) {
+		return v, nil
+	}
+}
+
+func logic(left, right expr, either bool) expr {
+	return func(item Result) (value, error) {
+		a, err := left(item)
+		if err != nil || a.flag == either {
+			return a, err
+		}
+		return right(item)
+	}
+}
+
+func versus(a, b value, op string) value {
+	var cmp int
+	switch a.kind {
+	case "num":
+		cmp = cmpnum(a.num, b.num)
+	case "text":
+		cmp = strings.Compare(a.text, b.text)
+	case "bool":
+		if a.flag != b.flag {
+			cmp = 1
+		}
+	}
+	out := false
+	switch op {
+	case "==":
+		out = cmp == 0
+	case "!=":
+		out = cmp != 0
+	case "<":
+		out = cmp < 0
+	case "<=":
+		out = cmp <= 0
+	case ">":
+		out = cmp > 0
+	case ">=":
+		out = cmp >= 0
+	}
+	return value{kind: "bool", flag: out}
+}
+
+func cmpnum(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
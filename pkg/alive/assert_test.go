@@ -0,0 +1,108 @@
+package alive
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAssert(t *testing.T) {
+	item := Result{
+		Code:    200,
+		Latency: 320 * time.Millisecond,
+		Size:    512,
+		State:   "up",
+		Proto:   "HTTP/2.0",
+		Header:  http.Header{"X-Cache": {"HIT"}},
+	}
+	cases := []struct {
+		raw  string
+		want bool
+	}{
+		{"status==200", true},
+		{"status!=200", false},
+		{"status>=200 && status<300", true},
+		{"latency<500ms", true},
+		{"latency<500", true},
+		{"latency>0.3s", true},
+		{"latency<=320ms", true},
+		{"latency<1m", true},
+		{"size>1000 || status==200", true},
+		{"status==500 || status==404 && size>0", false},
+		{"(status==500 || status==200) && size>0", true},
+		{"status==200 || status==404 && size>100000", true},
+		{"!status==500", true},
+		{"!(status==200 && size>0)", false},
+		{"!!true", true},
+		{"state=='up' && proto==\"HTTP/2.0\"", true},
+		{"header('x-cache')=='HIT'", true},
+		{"header('x-missing')==''", true},
+		{"note<'a'", true},
+		{"true==false", false},
+	}
+	for _, tc := range cases {
+		fn, err := Assert(tc.raw)
+		if err != nil {
+			t.Errorf("Assert(%q) error: %v", tc.raw, err)
+			continue
+		}
+		got, err := fn(item)
+		if err != nil || got != tc.want {
+			t.Errorf("Assert(%q) = %v, %v, want %v", tc.raw, got, err, tc.want)
+		}
+	}
+}
+
+func TestAssertErrors(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want string
+	}{
+		{"", "empty expression"},
+		{"status", "must be a comparison"},
+		{"'up'", "must be a comparison"},
+		{"status==", "unexpected end"},
+		{"status==200)", "unexpected \")\""},
+		{"(status==200", "missing )"},
+		{"status=='x'", "cannot compare num with text"},
+		{"status==200 && latency=='x'", "cannot compare num with text"},
+		{"status==0 || size", "&& and || need comparisons"},
+		{"status==0 && 'x'", "&& and || need comparisons"},
+		{"!size", "! needs a boolean"},
+		{"true<false", "does not apply to booleans"},
+		{"bogus==1", "unknown field"},
+		{"latency<5parsecs", "bad duration"},
+		{"header(x)=='y'", "header name must be quoted"},
+		{"status==200 $", "unexpected \"$\""},
+		{"note=='open", "unterminated string"},
+	}
+	for _, tc := range cases {
+		_, err := Assert(tc.raw)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Assert(%q) error = %v, want %q", tc.raw, err, tc.want)
+		}
+	}
+}
+
+func TestAssertShortCircuit(t *testing.T) {
+	calls := 0
+	fields["calls"] = func(Result) value {
+		calls++
+		return value{kind: "num"}
+	}
+	defer delete(fields, "calls")
+	for _, raw := range []string{"status==200 || calls==0", "status==500 && calls==0"} {
+		fn, err := Assert(raw)
+		if err != nil {
+			t.Fatalf("Assert(%q) error: %v", raw, err)
+		}
+		calls = 0
+		if _, err := fn(Result{Code: 200}); err != nil {
+			t.Fatalf("Assert(%q) eval error: %v", raw, err)
+		}
+		if calls != 0 {
+			t.Errorf("Assert(%q) evaluated the right side %d times", raw, calls)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package alive
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"sync/atomic"
+)
+
+type budget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+func (b *budget) reserve(want int64) int64 {
+	for {
+		cur := b.used.Load()
+		left := b.limit - cur
+		if left <= 0 {
+			return 0
+		}
+		got := min(want, left)
+		if b.used.CompareAndSwap(cur, cur+got) {
+			return got
+		}
+	}
+}
+
+func (b *budget) read(body io.Reader, sink io.Writer) (int64, bool, error) {
+	buf := make([]byte, 32*1024)
+	var total int64
+	for {
+		got := int64(len(buf))
+		if b != nil {
+			if got = b.reserve(got); got == 0 {
+				return total, false, nil
+			}
+		}
+		n, err := body.Read(buf[:got])
+		total += int64(n)
+		if rest := got - int64(n); b != nil && rest > 0 {
+			b.used.Add(-rest)
+		}
+		if n > 0 && sink != nil {
+			sink.Write(buf[:n])
+		}
+		if err == io.EOF {
+			return total, true, nil
+		}
+		if err != nil {
+			return total, true, err
+		}
+	}
+}
+
+type capped struct {
+	src  io.Reader
+	left int64
+	cut  bool
+}
+
+func limit(src io.Reader, most int64) *capped {
+	if most <= 0 {
+		most = math.MaxInt64
+	}
+	return &capped{src: src, left: most}
+}
+
+func (c *capped) Read(buf []byte) (int, error) {
+	if c.left <= 0 {
+		var one [1]byte
+		for {
+			n, err := c.src.Read(one[:])
+			if n > 0 {
+				c.cut = true
+			}
+			if n > 0 || err != nil {
+				return 0, io.EOF
+			}
+		}
+	}
+	if int64(len(buf)) > c.left {
+		buf = buf[:c.left]
+	}
+	n, err := c.src.Read(buf)
+	c.left -= int64(n)
+	return n, err
+}
+
+type counter struct {
+	src io.Reader
+	n   int64
+}
+
+func (c *counter) Read(buf []byte) (int, error) {
+	n, err := c.src.Read(buf)
+	c.n += int64(n)
+	return n, err
+}
+
+type finder struct {
+	needle []byte
+	tail   []byte
+	found  bool
+}
+
+func (f *finder) Write(buf []byte) (int, error) {
+	if f.found || len(f.needle) == 0 {
+		return len(buf), nil
+	}
+	data := append(append([]byte(nil), f.tail...), buf...)
+	if bytes.Contains(data, f.needle) {
+		f.found = true
+		return len(buf), nil
+	}
+	keep := min(len(f.needle)-1, len(data))
+	f.tail = append(f.tail[:0], data[len(data)-keep:]...)
+	return len(buf), nil
+}
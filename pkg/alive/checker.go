@@ -0,0 +1,126 @@
+package alive
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Checker runs checks with a fixed configuration. Build one with New; the
+// zero value checks over http with Defaults.
+type Checker struct {
+	opt   Options
+	probe Probe
+}
+
+// Option changes how a Checker behaves.
+type Option func(*Checker)
+
+// New returns a Checker that starts from Defaults, probes over http and
+// then applies opts in order.
+func New(opts ...Option) *Checker {
+	c := &Checker{opt: Defaults(), probe: HTTP}
+	for _, apply := range opts {
+		apply(c)
+	}
+	return c
+}
+
+// WithTimeout sets the per-check timeout.
+func WithTimeout(span time.Duration) Option {
+	return func(c *Checker) { c.opt.Timeout = span }
+}
+
+// WithWorkers sets how many checks run at once.
+func WithWorkers(count int) Option {
+	return func(c *Checker) { c.opt.Workers = max(count, 1) }
+}
+
+// WithHeaders adds headers to every request.
+func WithHeaders(head http.Header) Option {
+	return func(c *Checker) {
+		if c.opt.Headers == nil {
+			c.opt.Headers = http.Header{}
+		}
+		for key, values := range head {
+			for _, value := range values {
+				c.opt.Headers.Add(key, value)
+			}
+		}
+	}
+}
+
+// WithInsecureTLS skips tls verification. Rows that relied on it are marked.
+func WithInsecureTLS() Option {
+	return func(c *Checker) { c.opt.Insecure = true }
+}
+
+// WithRetries retries failed checks up to count times with backoff.
+func WithRetries(count int) Option {
+	return func(c *Checker) { c.opt.Retries = count }
+}
+
+// WithMethod sets the request method.
+func WithMethod(method string) Option {
+	return func(c *Checker) { c.opt.Method = method }
+}
+
+// WithProbe checks targets with probe instead of HTTP, e.g. TCP or DNS.
+func WithProbe(probe Probe) Option {
+	return func(c *Checker) { c.probe = probe }
+}
+
+// WithOptions replaces the whole configuration with opt. Zero Timeout,
+// Workers, Method, MaxRedirects, WarnAt, UserAgent, MaxBody and RetryOn
+// fall back to Defaults.
+func WithOptions(opt Options) Option {
+	return func(c *Checker) { c.opt = opt }
+}
+
+// Check checks one target.
+func (c *Checker) Check(target string) Result {
+	opt, probe := c.setup()
+	return probe(target, opt)
+}
+
+// CheckMany checks every line in input concurrently. Lines may carry
+// per-target overrides (timeout=, expect=, @tag), see Tune. When ctx is done
+// checks in flight are aborted and every unfinished target comes back
+// cancelled.
+func (c *Checker) CheckMany(ctx context.Context, input []string) []Result {
+	opt, probe := c.setup()
+	return run(ctx, input, opt, probe)
+}
+
+func (c *Checker) setup() (Options, Probe) {
+	opt, base := c.opt, Defaults()
+	if opt.Timeout <= 0 {
+		opt.Timeout = base.Timeout
+	}
+	if opt.Workers < 1 {
+		opt.Workers = base.Workers
+	}
+	if opt.Method == "" {
+		opt.Method = base.Method
+	}
+	if opt.MaxRedirects == 0 {
+		opt.MaxRedirects = base.MaxRedirects
+	}
+	if opt.WarnAt == 0 {
+		opt.WarnAt = base.WarnAt
+	}
+	if opt.UserAgent == "" {
+		opt.UserAgent = base.UserAgent
+	}
+	if opt.MaxBody == 0 {
+		opt.MaxBody = base.MaxBody
+	}
+	if opt.RetryOn == nil {
+		opt.RetryOn = base.RetryOn
+	}
+	probe := c.probe
+	if probe == nil {
+		probe = HTTP
+	}
+	return opt, probe
+}
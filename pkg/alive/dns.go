@@ -0,0 +1,26 @@
+package alive
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DNS checks that a hostname resolves, noting its addresses.
+func DNS(item string, opt Options) Result {
+	host := strings.TrimSuffix(item, ".")
+	if host == "" || strings.ContainsAny(host, "/: ") {
+		return Result{Target: item, State: "invalid", Note: "expected a hostname"}
+	}
+	ctx, stop := context.WithTimeout(parent(opt), opt.Timeout)
+	defer stop()
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return Result{Target: item, State: "down", Latency: time.Since(start), Note: Reason(err)}
+	}
+	sort.Strings(addrs)
+	return Result{Target: item, State: "up", Latency: time.Since(start), Note: strings.Join(addrs, ",")}
+}
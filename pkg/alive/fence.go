@@ -0,0 +1,84 @@
+package alive
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+var (
+	errblocked = errors.New("host not allowed")
+	errprivate = errors.New("private address not allowed")
+)
+
+// Fence limits which hosts and addresses a check may dial. Fenced checks
+// never go through a proxy, so the fence always sees the target.
+type Fence struct {
+	Hosts   []string
+	CIDRs   []*net.IPNet
+	Private bool
+}
+
+func (f *Fence) name(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, item := range f.Hosts {
+		if host == item || strings.HasPrefix(item, ".") && strings.HasSuffix(host, item) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Fence) ip(ip net.IP) error {
+	for _, block := range f.CIDRs {
+		if block.Contains(ip) {
+			return nil
+		}
+	}
+	if !f.Private && internal(ip) {
+		return errprivate
+	}
+	if len(f.Hosts) > 0 || len(f.CIDRs) > 0 {
+		return errblocked
+	}
+	return nil
+}
+
+func internal(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+func (f *Fence) dial(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if f.name(host) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range addrs {
+			ips = append(ips, item.IP)
+		}
+	}
+	for _, ip := range ips {
+		if err := f.ip(ip); err != nil {
+			return nil, err
+		}
+	}
+	for i, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil || i == len(ips)-1 {
+			return conn, err
+		}
+	}
+	return nil, errblocked
+}
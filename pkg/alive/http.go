@@ -0,0 +1,538 @@
+package alive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptrace"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+)
+
+var errloop = errors.New("too many redirects")
+
+// HTTP checks a url.
+func HTTP(item string, opt Options) Result {
+	used := strings.TrimSpace(item)
+	if opt.StrictURL {
+		if err := strict(used); err != nil {
+			return Result{Target: strip(used), State: "invalid", Note: err.Error()}
+		}
+	}
+	if err := Valid(used); err != nil {
+		return Result{Target: strip(used), State: "invalid", Note: err.Error()}
+	}
+	shown := strip(used)
+	span := opt.Timeout
+	ctx, stop := context.WithTimeout(parent(opt), span)
+	defer stop()
+	start := time.Now()
+	var trace *phases
+	if opt.Trace {
+		trace = &phases{start: start}
+		ctx = httptrace.WithClientTrace(ctx, trace.hook())
+	}
+	remote := ""
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) {
+		if host, _, err := net.SplitHostPort(info.Conn.RemoteAddr().String()); err == nil {
+			remote = host
+		}
+	}})
+	req, err := request(ctx, opt.Method, used, opt)
+	if err != nil {
+		return Result{Target: shown, State: "invalid", Note: err.Error()}
+	}
+	hops := 0
+	cli := &http.Client{Timeout: span, CheckRedirect: func(next *http.Request, via []*http.Request) error {
+		if opt.NoRedirect {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > opt.MaxRedirects {
+			return errloop
+		}
+		hops = len(via)
+		return nil
+	}}
+	if opt.shared != nil {
+		cli.Transport = opt.shared
+	} else if tr := transport(opt); tr != nil {
+		defer tr.CloseIdleConnections()
+		cli.Transport = tr
+	}
+	cli.Jar, _ = cookiejar.New(nil)
+	cli.Jar.SetCookies(req.URL, opt.Cookies)
+	var res *http.Response
+	tries := 0
+	for {
+		tries++
+		hops = 0
+		res, err = send(cli, req)
+		if tries > opt.Retries || !retryable(res, err, opt.RetryOn) {
+			break
+		}
+		wait := 100 * time.Millisecond << (tries - 1)
+		if end, ok := ctx.Deadline(); ok && time.Until(end) <= wait {
+			break
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+		select {
+		case <-time.After(wait):
+			continue
+		case <-ctx.Done():
+		}
+		res, err = nil, ctx.Err()
+		break
+	}
+	if opt.Retries == 0 {
+		tries = 0
+	}
+	if errors.Is(err, errloop) {
+		out := Result{Target: shown, State: "warn", Latency: time.Since(start), Note: errloop.Error(), Attempts: tries, Redirects: hops}
+		if res != nil {
+			out.Code = res.StatusCode
+		}
+		if trace != nil {
+			trace.fill(&out)
+		}
+		return out
+	}
+	if err != nil {
+		out := Result{Target: shown, State: "down", Latency: time.Since(start), Note: Reason(err), Attempts: tries}
+		if out.Note == "timeout" && remote == "" {
+			out.Note = "connect-timeout"
+		}
+		if errors.Is(err, errblocked) || errors.Is(err, errprivate) {
+			out.State, out.Latency = "invalid", 0
+		}
+		if trace != nil {
+			trace.fill(&out)
+		}
+		return out
+	}
+	defer res.Body.Close()
+	state := "up"
+	issue := ""
+	switch {
+	case len(opt.Expect) > 0 && !opt.Expect[res.StatusCode]:
+		state = "warn"
+		issue = fmt.Sprintf("unexpected status %d", res.StatusCode)
+	case len(opt.Expect) == 0 && res.StatusCode >= opt.WarnAt:
+		state = "warn"
+	}
+	size := res.ContentLength
+	if size < 0 {
+		size = 0
+	}
+	out := Result{Target: shown, State: state, Code: res.StatusCode, Size: size, Note: issue, Attempts: tries, IP: remote, Proto: res.Proto, Redirects: hops}
+	if place := strip(res.Request.URL.String()); place != shown {
+		out.Final = place
+	}
+	if opt.NoRedirect && out.Note == "" && res.StatusCode >= 300 && res.StatusCode < 400 {
+		if place := res.Header.Get("Location"); place != "" {
+			out.Note = "location " + place
+		}
+	}
+	var stalled error
+	whole := opt.budget != nil || opt.ExpectBody != "" || opt.ExpectRegex != nil || opt.MeasureBody
+	if whole {
+		var src io.Reader = res.Body
+		var packed *counter
+		if opt.MeasureBody && !res.Uncompressed && res.Header.Get("Content-Encoding") == "gzip" {
+			packed = &counter{src: res.Body}
+			src = packed
+			if zip, err := gzip.NewReader(packed); err == nil {
+				src = zip
+			} else {
+				out.Note = "bad gzip body"
+			}
+		}
+		body := limit(src, opt.MaxBody)
+		find := &finder{needle: []byte(opt.ExpectBody)}
+		var kept bytes.Buffer
+		var sink io.Writer = find
+		if opt.ExpectRegex != nil {
+			sink = io.MultiWriter(find, &kept)
+		}
+		read, ok, err := opt.budget.read(body, sink)
+		out.Size = read
+		stalled = err
+		if body.cut {
+			out.Note = "truncated"
+		}
+		if !ok {
+			out.Note = "byte budget exhausted"
+		}
+		sent := read
+		if packed != nil {
+			sent = packed.n
+		}
+		if ok && !body.cut && res.ContentLength >= 0 && sent != res.ContentLength {
+			out.Note = fmt.Sprintf("size mismatch (content-length %d)", res.ContentLength)
+		}
+		if packed != nil && out.Note == "" {
+			out.Note = fmt.Sprintf("gzip %d bytes", packed.n)
+		}
+		if ok && opt.ExpectBody != "" && !find.found {
+			out.State = "warn"
+			out.Note = "body mismatch"
+		}
+		if ok && opt.ExpectRegex != nil && !opt.ExpectRegex.Match(kept.Bytes()) {
+			out.State = "warn"
+			out.Note = "regex no match"
+		}
+	}
+	out.Latency = time.Since(start)
+	if !whole {
+		rest := limit(res.Body, opt.MaxBody)
+		read, ok, _ := opt.budget.read(rest, nil)
+		if ok && !rest.cut && res.ContentLength < 0 {
+			out.Size = read
+		}
+	}
+	unpinned := false
+	if res.TLS != nil {
+		out.ALPN = res.TLS.NegotiatedProtocol
+		out.TLS = tls.VersionName(res.TLS.Version)
+		if opt.ALPN != "" && out.ALPN != opt.ALPN {
+			out.State = "warn"
+			out.Note = "alpn mismatch (" + dash(out.ALPN) + ")"
+		}
+		if len(res.TLS.PeerCertificates) > 0 {
+			out.Cert = res.TLS.PeerCertificates[0]
+			out.Expiry = out.Cert.NotAfter
+			left := time.Until(out.Expiry)
+			switch {
+			case left <= 0:
+				out.State = "warn"
+				out.Note = "cert expired"
+			case opt.CertWarnDays > 0 && left.Hours()/24 < float64(opt.CertWarnDays):
+				out.State = "warn"
+				out.Note = fmt.Sprintf("cert expires in %dd", int(left.Hours()/24))
+			}
+			sum := sha256.Sum256(out.Cert.Raw)
+			unpinned = len(opt.Pins) > 0 && !opt.Pins[base64.StdEncoding.EncodeToString(sum[:])]
+		}
+	}
+	if opt.Assert != nil {
+		out.Header = res.Header
+		ok, err := opt.Assert(out)
+		switch {
+		case err != nil:
+			out.State = "warn"
+			out.Note = err.Error()
+		case !ok:
+			out.State = "warn"
+			out.Note = "assert failed"
+		}
+	}
+	if opt.SlowAt > 0 && out.State == "up" && out.Latency > opt.SlowAt {
+		out.State = "warn"
+		out.Note = fmt.Sprintf("slow (%s)", out.Latency.Round(time.Millisecond))
+	}
+	if req.URL.Scheme == "https" && res.Request.URL.Scheme == "http" {
+		out.State = "warn"
+		out.Note = "downgraded to http"
+	}
+	if stalled != nil && Reason(stalled) == "timeout" {
+		out.State = "down"
+		out.Note = "body-timeout"
+	}
+	if unpinned {
+		out.State = "down"
+		out.Note = "pin mismatch"
+	}
+	if opt.Insecure && res.TLS != nil {
+		out.Note = strings.TrimSpace(out.Note + " (insecure)")
+	}
+	if trace != nil {
+		trace.fill(&out)
+	}
+	return out
+}
+
+func strip(raw string) string {
+	part, err := url.Parse(raw)
+	if err != nil || part.User == nil {
+		return raw
+	}
+	part.User = nil
+	return part.String()
+}
+
+func send(cli *http.Client, req *http.Request) (*http.Response, error) {
+	res, err := cli.Do(fresh(req))
+	if err != nil || req.Method != http.MethodHead || res.StatusCode != http.StatusMethodNotAllowed {
+		return res, err
+	}
+	res.Body.Close()
+	again := fresh(req)
+	again.Method = http.MethodGet
+	return cli.Do(again)
+}
+
+func fresh(req *http.Request) *http.Request {
+	out := req.Clone(req.Context())
+	if req.GetBody != nil {
+		out.Body, _ = req.GetBody()
+	}
+	return out
+}
+
+func retryable(res *http.Response, err error, on map[string]bool) bool {
+	if errors.Is(err, errloop) || errors.Is(err, errblocked) || errors.Is(err, errprivate) {
+		return false
+	}
+	if err != nil {
+		why := Reason(err)
+		if why == "connect-timeout" {
+			why = "timeout"
+		}
+		return on[why]
+	}
+	if res.StatusCode == http.StatusTooManyRequests {
+		return on["429"]
+	}
+	return res.StatusCode >= 500 && on["5xx"]
+}
+
+func request(ctx context.Context, method, target string, opt Options) (*http.Request, error) {
+	var body io.Reader
+	if opt.Body != nil {
+		body = bytes.NewReader(opt.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if opt.UserAgent == "-" {
+		req.Header.Set("User-Agent", "")
+	} else {
+		req.Header.Set("User-Agent", opt.UserAgent)
+	}
+	if opt.Body != nil {
+		kind := "text/plain; charset=utf-8"
+		if json.Valid(opt.Body) {
+			kind = "application/json"
+		}
+		req.Header.Set("Content-Type", kind)
+	}
+	if opt.MeasureBody {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	if user, pass, ok := strings.Cut(opt.BasicAuth, ":"); ok {
+		req.SetBasicAuth(user, pass)
+	} else if req.URL.User != nil {
+		pass, _ := req.URL.User.Password()
+		req.SetBasicAuth(req.URL.User.Username(), pass)
+	}
+	for key, list := range opt.Headers {
+		if key == "Host" {
+			req.Host = list[0]
+			continue
+		}
+		req.Header[key] = list
+	}
+	return req, nil
+}
+
+func transport(opt Options) *http.Transport {
+	if opt.pin == "" && !opt.Insecure && opt.ConnectTimeout == 0 && len(opt.Resolve) == 0 && opt.Family == "" && opt.Proxy == nil && opt.HTTPVersion == "" && opt.Fence == nil && opt.ClientCert == nil && !opt.NoKeepAlive {
+		return nil
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.DisableKeepAlives = opt.NoKeepAlive
+	if opt.Proxy != nil {
+		tr.Proxy = http.ProxyURL(opt.Proxy)
+	}
+	if opt.Fence != nil {
+		tr.Proxy = nil
+	}
+	switch opt.HTTPVersion {
+	case "1":
+		tr.Protocols = new(http.Protocols)
+		tr.Protocols.SetHTTP1(true)
+	case "2":
+		tr.Protocols = new(http.Protocols)
+		tr.Protocols.SetHTTP2(true)
+		tr.Protocols.SetUnencryptedHTTP2(true)
+	}
+	if opt.Insecure || opt.ClientCert != nil {
+		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: opt.Insecure}
+	}
+	if opt.ClientCert != nil {
+		tr.TLSClientConfig.Certificates = []tls.Certificate{*opt.ClientCert}
+	}
+	if opt.ConnectTimeout > 0 {
+		tr.TLSHandshakeTimeout = opt.ConnectTimeout
+	}
+	dialer := &net.Dialer{Timeout: opt.ConnectTimeout, KeepAlive: 30 * time.Second}
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ip := opt.Resolve[strings.ToLower(addr)]
+		if opt.pin != "" {
+			ip = opt.pin
+		}
+		if ip != "" {
+			_, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			addr = net.JoinHostPort(ip, port)
+		}
+		if opt.Family != "" {
+			network = opt.Family
+		}
+		if opt.Fence != nil {
+			return opt.Fence.dial(ctx, dialer, network, addr)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return tr
+}
+
+// Valid reports why raw is not a checkable http or https url.
+func Valid(raw string) error {
+	part, err := url.ParseRequestURI(raw)
+	if err != nil {
+		return errors.New("bad url")
+	}
+	if part.Scheme != "http" && part.Scheme != "https" {
+		return errors.New("scheme must be http or https")
+	}
+	host := part.Hostname()
+	if host == "" {
+		return errors.New("missing host")
+	}
+	if strings.Contains(host, " ") {
+		return errors.New("bad host")
+	}
+	if strings.HasPrefix(part.Host, "[") {
+		if addr, err := netip.ParseAddr(host); err != nil || !addr.Is6() {
+			return errors.New("bad host")
+		}
+	} else if strings.Contains(host, ":") {
+		return errors.New("bad host")
+	}
+	if port := part.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return errors.New("bad port")
+		}
+	}
+	return nil
+}
+
+func strict(raw string) error {
+	if strings.ContainsFunc(raw, unicode.IsControl) {
+		return errors.New("control character in url")
+	}
+	part, err := url.Parse(raw)
+	if err != nil {
+		return nil
+	}
+	if part.User != nil {
+		return errors.New("credentials in url")
+	}
+	if part.Fragment != "" || strings.HasSuffix(raw, "#") {
+		return errors.New("fragment in url")
+	}
+	return nil
+}
+
+func classify(err error) string {
+	var dns *net.DNSError
+	var cert *tls.CertificateVerificationError
+	var unknown x509.UnknownAuthorityError
+	var name x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &dns):
+		if dns.IsTimeout {
+			return "timeout"
+		}
+		return "dns"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return "reset"
+	case errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EHOSTUNREACH):
+		return "unreachable"
+	case errors.As(err, &cert), errors.As(err, &unknown), errors.As(err, &name), errors.As(err, &invalid):
+		return "tls"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "eof"
+	}
+	return ""
+}
+
+// Reason maps a check error to a short note such as timeout or refused.
+func Reason(err error) string {
+	if errors.Is(err, errblocked) {
+		return errblocked.Error()
+	}
+	if errors.Is(err, errprivate) {
+		return errprivate.Error()
+	}
+	var op *net.OpError
+	if errors.As(err, &op) && op.Op == "proxyconnect" {
+		return "proxy"
+	}
+	if errors.As(err, &op) && op.Op == "dial" && op.Timeout() {
+		return "connect-timeout"
+	}
+	if strings.Contains(err.Error(), "TLS handshake timeout") {
+		return "connect-timeout"
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return "timeout"
+	}
+	if errors.Is(err, context.Canceled) {
+		return "cancelled"
+	}
+	if kind := classify(err); kind != "" {
+		return kind
+	}
+	text := strings.ToLower(err.Error())
+	if strings.Contains(text, "deadline exceeded") {
+		return "timeout"
+	}
+	if strings.Contains(text, "no such host") {
+		return "dns"
+	}
+	if strings.Contains(text, "connection refused") {
+		return "refused"
+	}
+	if strings.Contains(text, "connection reset by peer") || strings.Contains(text, "broken pipe") {
+		return "reset"
+	}
+	if strings.Contains(text, "network is unreachable") || strings.Contains(text, "no route to host") {
+		return "unreachable"
+	}
+	if strings.HasSuffix(text, "eof") {
+		return "eof"
+	}
+	if strings.Contains(text, "no suitable address") || strings.Contains(text, "non-ipv4") || strings.Contains(text, "non-ipv6") {
+		return "no-address"
+	}
+	if strings.Contains(text, "certificate") {
+		return "tls"
+	}
+	return "error"
+}
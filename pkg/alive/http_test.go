@@ -0,0 +1,210 @@
+package alive
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestValid(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want string
+	}{
+		{"https://example.com", ""},
+		{"https://example.com:8443/path", ""},
+		{"https://[2001:db8::1]/", ""},
+		{"https://[2001:db8::1]:8443/", ""},
+		{"http://[::1]", ""},
+		{"http://[fe80::1%25en0]/", ""},
+		{"http://[fe80::1%25en0]:8080/", ""},
+		{"http://2001:db8::1/", "bad url"},
+		{"http://[1.2.3.4]/", "bad url"},
+		{"http://[example.com]/", "bad url"},
+		{"http://example.com:0/", "bad port"},
+		{"http://example.com:65535/", ""},
+		{"http://example.com:65536/", "bad port"},
+		{"http://[::1]:70000/", "bad port"},
+		{"ftp://example.com", "scheme must be http or https"},
+		{"http://", "missing host"},
+		{"example.com", "bad url"},
+	}
+	for _, tc := range cases {
+		got := ""
+		if err := Valid(tc.raw); err != nil {
+			got = err.Error()
+		}
+		if got != tc.want {
+			t.Errorf("Valid(%q) = %q, want %q", tc.raw, got, tc.want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	wrap := func(op string, err error) error {
+		return &url.Error{Op: "Get", URL: "http://example.com", Err: &net.OpError{Op: op, Net: "tcp", Err: os.NewSyscallError(op, err)}}
+	}
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{&net.DNSError{Err: "lookup failed", Name: "example.com", IsNotFound: true}, "dns"},
+		{&net.DNSError{Err: "lookup failed", Name: "example.com", IsTimeout: true}, "timeout"},
+		{wrap("connect", syscall.ECONNREFUSED), "refused"},
+		{wrap("read", syscall.ECONNRESET), "reset"},
+		{wrap("write", syscall.EPIPE), "reset"},
+		{wrap("connect", syscall.ENETUNREACH), "unreachable"},
+		{wrap("connect", syscall.EHOSTUNREACH), "unreachable"},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: &tls.CertificateVerificationError{Err: errors.New("bad chain")}}, "tls"},
+		{fmt.Errorf("verify: %w", x509.UnknownAuthorityError{}), "tls"},
+		{&url.Error{Op: "Get", URL: "http://example.com", Err: io.ErrUnexpectedEOF}, "eof"},
+		{&url.Error{Op: "Get", URL: "http://example.com", Err: io.EOF}, "eof"},
+		{errors.New("connection refused"), ""},
+	}
+	for _, tc := range cases {
+		if got := classify(tc.err); got != tc.want {
+			t.Errorf("classify(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestReason(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{context.DeadlineExceeded, "timeout"},
+		{&url.Error{Op: "Get", URL: "http://example.com", Err: context.Canceled}, "cancelled"},
+		{&url.Error{Op: "Get", URL: "http://example.com", Err: context.DeadlineExceeded}, "timeout"},
+		{fmt.Errorf("read body: %w", os.ErrDeadlineExceeded), "timeout"},
+		{&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, "reset"},
+		{&net.DNSError{Err: "lookup failed", Name: "example.com", IsNotFound: true}, "dns"},
+		{errblocked, "host not allowed"},
+		{errors.New("dial tcp: connection refused"), "refused"},
+		{errors.New("read: connection reset by peer"), "reset"},
+		{errors.New("x509: certificate signed by unknown authority"), "tls"},
+		{errors.New("something else"), "error"},
+	}
+	for _, tc := range cases {
+		if got := Reason(tc.err); got != tc.want {
+			t.Errorf("Reason(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestAssertKeepsWarn(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer srv.Close()
+	cases := []struct {
+		name   string
+		tune   func(*Options)
+		assert string
+		state  string
+		note   string
+	}{
+		{"pass", func(*Options) {}, "status==200", "up", ""},
+		{"fail", func(*Options) {}, "status==204", "warn", "assert failed"},
+		{"expect", func(opt *Options) { opt.Expect = map[int]bool{204: true} }, "latency<5s", "warn", "unexpected status 200"},
+		{"body", func(opt *Options) { opt.ExpectBody = "Nope" }, "status==200", "warn", "body mismatch"},
+		{"warn-at", func(opt *Options) { opt.WarnAt = 200 }, "status==200", "warn", ""},
+	}
+	for _, tc := range cases {
+		opt := Defaults()
+		tc.tune(&opt)
+		check, err := Assert(tc.assert)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opt.Assert = check
+		got := HTTP(srv.URL, opt)
+		if got.State != tc.state || got.Note != tc.note {
+			t.Errorf("%s: got %s %q, want %s %q", tc.name, got.State, got.Note, tc.state, tc.note)
+		}
+	}
+}
+
+func TestPinMismatch(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer srv.Close()
+	opt := Defaults()
+	opt.Insecure = true
+	opt.Pins = map[string]bool{"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=": true}
+	check, err := Assert("status==200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt.Assert = check
+	got := HTTP(srv.URL, opt)
+	if got.State != "down" || got.Note != "pin mismatch (insecure)" {
+		t.Errorf("got %s %q, want down \"pin mismatch (insecure)\"", got.State, got.Note)
+	}
+	sum := sha256.Sum256(srv.Certificate().Raw)
+	opt.Pins = map[string]bool{base64.StdEncoding.EncodeToString(sum[:]): true}
+	if got := HTTP(srv.URL, opt); got.State != "up" {
+		t.Errorf("matching pin: got %s %q, want up", got.State, got.Note)
+	}
+}
+
+func TestCheckerFillsDefaults(t *testing.T) {
+	hits, agent := 0, ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		agent = r.UserAgent()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	got := New(WithOptions(Options{Retries: 2})).Check(srv.URL)
+	if got.State != "warn" || hits != 3 {
+		t.Errorf("got %s after %d attempts, want warn after 3", got.State, hits)
+	}
+	if agent != "alive/1" {
+		t.Errorf("user agent = %q, want alive/1", agent)
+	}
+	New(WithOptions(Options{UserAgent: "-"})).Check(srv.URL)
+	if agent != "" {
+		t.Errorf("user agent = %q, want none", agent)
+	}
+	opt, _ := New(WithOptions(Options{})).setup()
+	if opt.MaxBody != Defaults().MaxBody || opt.RetryOn == nil {
+		t.Errorf("setup left MaxBody %d, RetryOn %v", opt.MaxBody, opt.RetryOn)
+	}
+}
+
+func TestCancelAbortsInFlight(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	rows := New(WithTimeout(10*time.Second)).CheckMany(ctx, []string{srv.URL})
+	if took := time.Since(start); took > 5*time.Second {
+		t.Fatalf("check ran %s after cancel", took)
+	}
+	if len(rows) != 1 || rows[0].State != "cancelled" || rows[0].Note != "in flight" {
+		t.Errorf("got %+v, want cancelled in flight", rows)
+	}
+}
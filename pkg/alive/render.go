@@ -0,0 +1,101 @@
+package alive
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Columns are the fields Render prints.
+var Columns = []string{"target", "state", "code", "latency", "size", "note"}
+
+// Render prints results as a tab separated table.
+func Render(results []Result) string {
+	if len(results) == 0 {
+		return "no targets\n"
+	}
+	var b strings.Builder
+	fmt.Fprintln(&b, strings.Join(Columns, "\t"))
+	for _, item := range results {
+		cells := make([]string, len(Columns))
+		for i, name := range Columns {
+			cells[i] = Field(item, name)
+		}
+		fmt.Fprintln(&b, strings.Join(cells, "\t"))
+	}
+	return b.String()
+}
+
+// Field formats one column of a result, "-" when empty.
+func Field(item Result, name string) string {
+	switch name {
+	case "target":
+		return item.Target
+	case "state":
+		return item.State
+	case "code":
+		if item.Code > 0 {
+			return strconv.Itoa(item.Code)
+		}
+	case "latency":
+		if item.Latency > 0 {
+			return item.Latency.Round(time.Millisecond).String()
+		}
+	case "size":
+		if item.Size > 0 {
+			return strconv.FormatInt(item.Size, 10)
+		}
+	case "attempts":
+		if item.Attempts > 0 {
+			return strconv.Itoa(item.Attempts)
+		}
+	case "final":
+		return dash(item.Final)
+	case "tag":
+		return dash(item.Tag)
+	case "ip":
+		return dash(item.IP)
+	case "proto":
+		return dash(item.Proto)
+	case "redirects":
+		if item.Code > 0 {
+			return strconv.Itoa(item.Redirects)
+		}
+	case "alpn":
+		return dash(item.ALPN)
+	case "tls":
+		return dash(item.TLS)
+	case "expiry":
+		if !item.Expiry.IsZero() {
+			return item.Expiry.UTC().Format("2006-01-02")
+		}
+	case "dns":
+		return timing(item.DNS)
+	case "connect":
+		return timing(item.Connect)
+	case "handshake":
+		return timing(item.Handshake)
+	case "ttfb":
+		return timing(item.TTFB)
+	case "change":
+		return dash(item.Change)
+	case "note":
+		return dash(item.Note)
+	}
+	return "-"
+}
+
+func timing(span time.Duration) string {
+	if span <= 0 {
+		return "-"
+	}
+	return span.Round(10 * time.Microsecond).String()
+}
+
+func dash(text string) string {
+	if text == "" {
+		return "-"
+	}
+	return text
+}
@@ -0,0 +1,41 @@
+package alive
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+// TCP checks that host:port accepts a connection.
+func TCP(item string, opt Options) Result {
+	host, port, err := net.SplitHostPort(item)
+	if err != nil {
+		return Result{Target: item, State: "invalid", Note: "expected host:port"}
+	}
+	if num, err := strconv.Atoi(port); err != nil || num < 1 || num > 65535 {
+		return Result{Target: item, State: "invalid", Note: "invalid port"}
+	}
+	if host == "" {
+		return Result{Target: item, State: "invalid", Note: "missing host"}
+	}
+	span := opt.Timeout
+	if opt.ConnectTimeout > 0 {
+		span = opt.ConnectTimeout
+	}
+	start := time.Now()
+	network := "tcp"
+	if opt.Family != "" {
+		network = opt.Family
+	}
+	dialer := &net.Dialer{Timeout: span}
+	conn, err := dialer.DialContext(parent(opt), network, item)
+	if err != nil {
+		return Result{Target: item, State: "down", Latency: time.Since(start), Note: Reason(err)}
+	}
+	out := Result{Target: item, State: "up", Latency: time.Since(start)}
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		out.IP = addr.IP.String()
+	}
+	conn.Close()
+	return out
+}
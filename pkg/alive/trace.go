@@ -0,0 +1,71 @@
+package alive
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+type phases struct {
+	mu      sync.Mutex
+	start   time.Time
+	dnsat   time.Time
+	dialat  time.Time
+	shakeat time.Time
+	dns     time.Duration
+	dial    time.Duration
+	shake   time.Duration
+	ttfb    time.Duration
+}
+
+func (p *phases) hook() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			p.mark(&p.dnsat)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			p.done(&p.dnsat, &p.dns)
+		},
+		ConnectStart: func(string, string) {
+			p.mark(&p.dialat)
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				p.done(&p.dialat, &p.dial)
+			}
+		},
+		TLSHandshakeStart: func() {
+			p.mark(&p.shakeat)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				p.done(&p.shakeat, &p.shake)
+			}
+		},
+		GotFirstResponseByte: func() {
+			p.done(&p.start, &p.ttfb)
+		},
+	}
+}
+
+func (p *phases) mark(at *time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	*at = time.Now()
+}
+
+func (p *phases) done(from *time.Time, into *time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	*into = time.Since(*from)
+}
+
+func (p *phases) fill(out *Result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out.DNS = p.dns
+	out.Connect = p.dial
+	out.Handshake = p.shake
+	out.TTFB = p.ttfb
+}